@@ -0,0 +1,162 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/spacemonkeygo/errors"
+	"github.com/spacemonkeygo/spacelog"
+
+	"sm/final/bot"
+	"sm/final/game"
+)
+
+var (
+	botList = flag.String("bots", "search-bot,battery-bot",
+		"comma separated built-in bots to play against each other")
+	mapList = flag.String("maps", "",
+		"comma separated map files or globs to play on; random maps if empty")
+	seeds     = flag.Int("seeds", 10, "games per pair of bots per map, in each seat order")
+	firstSeed = flag.Int64("seed", 1, "seed of the first game")
+	parallel  = flag.Int("parallel", runtime.NumCPU(), "games to play at once")
+	maxTurns  = flag.Int("max-turns", 10000, "turns before a game is called a draw")
+	jsonOut   = flag.String("json", "", "file to write the report to as JSON, or - for stdout")
+
+	ArenaError = errors.NewClass("arena error")
+
+	logger = spacelog.GetLogger()
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [flags]\n\nbuilt-in bots: %s\n\n",
+			os.Args[0], strings.Join(bot.Names(), ", "))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	spacelog.Setup("arena", spacelog.SetupConfig{
+		Output: "stderr",
+		Format: "{{.Message}}"})
+	err := Main()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
+
+func split(list string) (items []string) {
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func maps() (paths []string, err error) {
+	for _, pattern := range split(*mapList) {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, ArenaError.Wrap(err)
+		}
+		if len(matches) == 0 {
+			return nil, ArenaError.New("no maps match %q", pattern)
+		}
+		paths = append(paths, matches...)
+	}
+	if len(paths) == 0 {
+		// a random map per seed
+		paths = []string{""}
+	}
+	return paths, nil
+}
+
+// matches pairs every bot up with every other one, on every map and seed,
+// with each of them taking the first seat once.
+func matches(bots, paths []string) (all []match) {
+	for i := range bots {
+		for j := i + 1; j < len(bots); j++ {
+			for _, path := range paths {
+				for n := 0; n < *seeds; n++ {
+					seed := *firstSeed + int64(n)
+					all = append(all,
+						match{Bots: []string{bots[i], bots[j]}, Map: path,
+							Seed: seed},
+						match{Bots: []string{bots[j], bots[i]}, Map: path,
+							Seed: seed})
+				}
+			}
+		}
+	}
+	return all
+}
+
+func Main() error {
+	bots := split(*botList)
+	if len(bots) < 2 {
+		return ArenaError.New("need at least two bots, got %d", len(bots))
+	}
+	for _, name := range bots {
+		if _, err := bot.New(name); err != nil {
+			return err
+		}
+	}
+	paths, err := maps()
+	if err != nil {
+		return err
+	}
+
+	config := game.DefaultConfig()
+	todo := matches(bots, paths)
+	results := make([]result, len(todo))
+	logger.Noticef("playing %d games, %d at a time", len(todo), *parallel)
+
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < *parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = play(config, todo[i], *maxTurns)
+				logger.Debugf("%s on %q seed %d: %d turns",
+					strings.Join(todo[i].Bots, " vs "), todo[i].Map,
+					todo[i].Seed, results[i].Turns)
+			}
+		}()
+	}
+	for i := range todo {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	report := newReport(results)
+	if *jsonOut != "-" {
+		err = report.WriteTable(os.Stdout)
+		if err != nil {
+			return err
+		}
+	}
+	if *jsonOut == "" {
+		return nil
+	}
+	out := os.Stdout
+	if *jsonOut != "-" {
+		out, err = os.Create(*jsonOut)
+		if err != nil {
+			return ArenaError.Wrap(err)
+		}
+		defer out.Close()
+	}
+	encoder := json.NewEncoder(out)
+	return ArenaError.Wrap(encoder.Encode(report))
+}
@@ -0,0 +1,154 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package main
+
+import (
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sm/final/bot"
+	"sm/final/game"
+	"sm/final/grid"
+)
+
+// match is one game to play: which bots play it, in seat order, on which
+// map, with which seed.
+type match struct {
+	Bots []string
+	Map  string
+	Seed int64
+}
+
+// seat is how one bot did in a game.
+type seat struct {
+	Bot       string
+	Status    game.GameStatus
+	Damage    int
+	Batteries int
+	Timeouts  int
+}
+
+type result struct {
+	Match match
+	Turns int
+	Seats []seat
+	Err   error
+}
+
+// play runs m to the end without a server, the renderer or any waiting
+// around, ticking the game's snapshot directly. A bot that takes longer than
+// the turn timeout to decide gets a noop for that turn, just like it would
+// on the server.
+func play(config *game.Config, m match, max_turns int) (r result) {
+	r.Match = m
+	rng := rand.New(rand.NewSource(m.Seed))
+
+	var the_map *grid.Map
+	if m.Map != "" {
+		var err error
+		the_map, err = grid.LoadMap(m.Map)
+		if err != nil {
+			r.Err = err
+			return r
+		}
+		if the_map.Preset != "" {
+			config, err = config.WithPreset(the_map.Preset)
+			if err != nil {
+				r.Err = err
+				return r
+			}
+		}
+	} else {
+		the_map = &grid.Map{Grid: grid.NewSeededRandom(config.Width,
+			config.Height, config.Walls, config.Enclosed, m.Seed)}
+	}
+	if config.Bounded {
+		the_map.Grid.SetBounded(true)
+	}
+
+	handicaps, err := game.ParseHandicaps(config.Handicaps)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+
+	snapshot := game.NewMapSnapshot(the_map)
+	bots := make([]bot.Bot, 0, len(m.Bots))
+	seats := map[grid.Owner]*seat{}
+	r.Seats = make([]seat, len(m.Bots))
+	for i, name := range m.Bots {
+		b, err := bot.New(name)
+		if err != nil {
+			r.Err = err
+			return r
+		}
+		player, err := snapshot.AddHandicappedPlayer(config, name,
+			handicaps[name], rng)
+		if err != nil {
+			r.Err = err
+			return r
+		}
+		bots = append(bots, b)
+		r.Seats[i].Bot = name
+		seats[player.Owner] = &r.Seats[i]
+	}
+
+	states := make([]game.TurnState, len(bots))
+	for i, player := range snapshot.Players {
+		states[i] = snapshot.TurnState(player.Owner)
+		states[i].Config = config.PlayerConfig(player)
+		states[i].Config.Bounded = snapshot.Grid.Bounded()
+		if m.Map != "" {
+			states[i].Config.Map = strings.TrimSuffix(filepath.Base(m.Map),
+				".map")
+		}
+	}
+	// the server counts the join as the first turn
+	snapshot.Turn++
+
+	for !snapshot.Done() && snapshot.Turn <= max_turns {
+		actions := map[grid.Owner]game.Command{}
+		for i, player := range snapshot.Players {
+			if !player.Alive() {
+				continue
+			}
+			start := time.Now()
+			command := bots[i].Decide(states[i])
+			if player.TurnTimeout > 0 &&
+				time.Since(start) > player.TurnTimeout {
+				seats[player.Owner].Timeouts++
+				command = game.Noop
+			}
+			actions[player.Owner] = command
+		}
+
+		var events []game.Event
+		snapshot, events = snapshot.Next(config, actions, rng)
+		for _, event := range events {
+			switch event.Type {
+			case game.PlayerHit:
+				if s, ok := seats[event.Source]; ok &&
+					event.Source != event.Owner {
+					s.Damage += event.Amount
+				}
+			case game.BatteryCollected:
+				seats[event.Owner].Batteries++
+			}
+		}
+		for i, player := range snapshot.Players {
+			states[i] = snapshot.TurnState(player.Owner)
+		}
+	}
+
+	r.Turns = snapshot.Turn - 1
+	for i, player := range snapshot.Players {
+		r.Seats[i].Status = snapshot.PlayerStatus(player)
+		if r.Seats[i].Status == game.Running {
+			// out of turns
+			r.Seats[i].Status = game.Draw
+		}
+	}
+	return r
+}
@@ -0,0 +1,192 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"sm/final/game"
+)
+
+// z for a 95% confidence interval
+const z = 1.96
+
+// Interval is a rate with its 95% Wilson score confidence interval.
+type Interval struct {
+	Rate float64 `json:"rate"`
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+func wilson(successes, trials int) Interval {
+	if trials == 0 {
+		return Interval{}
+	}
+	n := float64(trials)
+	p := float64(successes) / n
+	denominator := 1 + z*z/n
+	center := (p + z*z/(2*n)) / denominator
+	margin := z * math.Sqrt(p*(1-p)/n+z*z/(4*n*n)) / denominator
+	return Interval{
+		Rate: p,
+		Low:  math.Max(0, center-margin),
+		High: math.Min(1, center+margin),
+	}
+}
+
+func (i Interval) String() string {
+	return fmt.Sprintf("%5.1f%% [%5.1f, %5.1f]", 100*i.Rate, 100*i.Low,
+		100*i.High)
+}
+
+// Record is how a bot did over a set of games.
+type Record struct {
+	Bot       string   `json:"bot"`
+	Opponent  string   `json:"opponent,omitempty"`
+	Games     int      `json:"games"`
+	Wins      int      `json:"wins"`
+	Losses    int      `json:"losses"`
+	Draws     int      `json:"draws"`
+	WinRate   Interval `json:"win_rate"`
+	LossRate  Interval `json:"loss_rate"`
+	DrawRate  Interval `json:"draw_rate"`
+	Turns     float64  `json:"avg_turns"`
+	Damage    float64  `json:"avg_damage"`
+	Batteries float64  `json:"avg_batteries"`
+	Timeouts  int      `json:"timeouts"`
+
+	turns, damage, batteries int
+}
+
+func (r *Record) add(res result, s seat) {
+	r.Games++
+	switch s.Status {
+	case game.Won:
+		r.Wins++
+	case game.Lost:
+		r.Losses++
+	default:
+		r.Draws++
+	}
+	r.turns += res.Turns
+	r.damage += s.Damage
+	r.batteries += s.Batteries
+	r.Timeouts += s.Timeouts
+}
+
+func (r *Record) finish() {
+	r.WinRate = wilson(r.Wins, r.Games)
+	r.LossRate = wilson(r.Losses, r.Games)
+	r.DrawRate = wilson(r.Draws, r.Games)
+	if r.Games > 0 {
+		r.Turns = float64(r.turns) / float64(r.Games)
+		r.Damage = float64(r.damage) / float64(r.Games)
+		r.Batteries = float64(r.batteries) / float64(r.Games)
+	}
+}
+
+// Report is everything the arena found out.
+type Report struct {
+	Games    int      `json:"games"`
+	Errors   []string `json:"errors,omitempty"`
+	Bots     []Record `json:"bots"`
+	Matchups []Record `json:"matchups"`
+}
+
+func newReport(results []result) *Report {
+	report := &Report{}
+	bots := map[string]*Record{}
+	matchups := map[string]*Record{}
+	record := func(records map[string]*Record, bot, opponent string) *Record {
+		key := bot + "\x00" + opponent
+		r, ok := records[key]
+		if !ok {
+			r = &Record{Bot: bot, Opponent: opponent}
+			records[key] = r
+		}
+		return r
+	}
+
+	for _, res := range results {
+		if res.Err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s on %q: %v",
+				strings.Join(res.Match.Bots, " vs "), res.Match.Map, res.Err))
+			continue
+		}
+		report.Games++
+		for i, s := range res.Seats {
+			record(bots, s.Bot, "").add(res, s)
+			for j, other := range res.Seats {
+				if i != j {
+					record(matchups, s.Bot, other.Bot).add(res, s)
+				}
+			}
+		}
+	}
+
+	for _, r := range bots {
+		r.finish()
+		report.Bots = append(report.Bots, *r)
+	}
+	for _, r := range matchups {
+		r.finish()
+		report.Matchups = append(report.Matchups, *r)
+	}
+	sortRecords(report.Bots)
+	sortRecords(report.Matchups)
+	return report
+}
+
+// sortRecords puts the best win rates first.
+func sortRecords(records []Record) {
+	sort.Sort(byWinRate(records))
+}
+
+type byWinRate []Record
+
+func (b byWinRate) Len() int      { return len(b) }
+func (b byWinRate) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byWinRate) Less(i, j int) bool {
+	if b[i].WinRate.Rate != b[j].WinRate.Rate {
+		return b[i].WinRate.Rate > b[j].WinRate.Rate
+	}
+	if b[i].Bot != b[j].Bot {
+		return b[i].Bot < b[j].Bot
+	}
+	return b[i].Opponent < b[j].Opponent
+}
+
+func (report *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "%d games\n\n", report.Games)
+	fmt.Fprintf(tw, "bot\tvs\tgames\tW/L/D\twin rate (95%% CI)\t"+
+		"turns\tdamage\tbatteries\ttimeouts\n")
+	write := func(r Record) {
+		opponent := r.Opponent
+		if opponent == "" {
+			opponent = "*"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d/%d/%d\t%s\t%.1f\t%.1f\t%.1f\t%d\n",
+			r.Bot, opponent, r.Games, r.Wins, r.Losses, r.Draws, r.WinRate,
+			r.Turns, r.Damage, r.Batteries, r.Timeouts)
+	}
+	for _, r := range report.Bots {
+		write(r)
+	}
+	if len(report.Matchups) > 2 {
+		// with only two bots the matchups say the same as the totals
+		fmt.Fprintf(tw, "\t\t\t\t\t\t\t\t\n")
+		for _, r := range report.Matchups {
+			write(r)
+		}
+	}
+	for _, err := range report.Errors {
+		fmt.Fprintf(tw, "error: %s\n", err)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,90 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package main
+
+import (
+	"math"
+	"testing"
+
+	"sm/final/game"
+)
+
+func TestWilson(t *testing.T) {
+	for _, test := range []struct {
+		successes, trials int
+		low, high         float64
+	}{
+		{0, 0, 0, 0},
+		{0, 10, 0, 0.2775},
+		{5, 10, 0.2366, 0.7634},
+		{10, 10, 0.7225, 1},
+		{50, 100, 0.4038, 0.5962},
+	} {
+		i := wilson(test.successes, test.trials)
+		if math.Abs(i.Low-test.low) > 0.0001 ||
+			math.Abs(i.High-test.high) > 0.0001 {
+			t.Errorf("%d/%d: expected [%.4f, %.4f], got [%.4f, %.4f]",
+				test.successes, test.trials, test.low, test.high, i.Low, i.High)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	game_result := func(a, b game.GameStatus, turns int) result {
+		return result{
+			Match: match{Bots: []string{"a", "b"}},
+			Turns: turns,
+			Seats: []seat{
+				{Bot: "a", Status: a, Damage: 10, Batteries: 1},
+				{Bot: "b", Status: b, Timeouts: 1},
+			},
+		}
+	}
+	report := newReport([]result{
+		game_result(game.Won, game.Lost, 10),
+		game_result(game.Won, game.Lost, 20),
+		game_result(game.Draw, game.Draw, 30),
+		{Match: match{Bots: []string{"a", "b"}}, Err: ArenaError.New("oops")},
+	})
+
+	if report.Games != 3 || len(report.Errors) != 1 {
+		t.Fatalf("expected 3 games and 1 error, got %d and %d",
+			report.Games, len(report.Errors))
+	}
+	if len(report.Bots) != 2 || len(report.Matchups) != 2 {
+		t.Fatalf("expected 2 bots and 2 matchups, got %d and %d",
+			len(report.Bots), len(report.Matchups))
+	}
+	a, b := report.Bots[0], report.Bots[1]
+	if a.Bot != "a" || a.Wins != 2 || a.Losses != 0 || a.Draws != 1 ||
+		a.Turns != 20 || a.Damage != 10 || a.Batteries != 1 {
+		t.Errorf("unexpected record for a: %+v", a)
+	}
+	if b.Bot != "b" || b.Wins != 0 || b.Losses != 2 || b.Draws != 1 ||
+		b.Timeouts != 3 {
+		t.Errorf("unexpected record for b: %+v", b)
+	}
+}
+
+func TestPlay(t *testing.T) {
+	config := game.DefaultConfig()
+	config.Width, config.Height, config.Walls = 8, 8, 4
+	config.TurnTimeout = 0
+	r := play(config, match{Bots: []string{"circle-bot", "battery-bot"},
+		Seed: 1}, 20)
+	if r.Err != nil {
+		t.Fatal(r.Err)
+	}
+	if r.Turns < 1 || r.Turns > 20 {
+		t.Fatalf("expected 1 to 20 turns, got %d", r.Turns)
+	}
+	if len(r.Seats) != 2 || r.Seats[0].Bot != "circle-bot" ||
+		r.Seats[1].Bot != "battery-bot" {
+		t.Fatalf("unexpected seats %+v", r.Seats)
+	}
+	for _, s := range r.Seats {
+		if s.Status == game.Running {
+			t.Errorf("%s is still running", s.Bot)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spacemonkeygo/errors"
+	"github.com/spacemonkeygo/spacelog"
+
+	"sm/final/client"
+	"sm/final/game"
+)
+
+var (
+	host       = flag.String("host", "localhost:8080", "host to connect to")
+	gameName   = flag.String("game", "yay:screen", "game name")
+	moniker    = flag.String("moniker", "bot-runner", "moniker to use")
+	team       = flag.Int("team", 0, "team to join on, or 0 for whichever the game picks")
+	handicap   = flag.String("handicap", "", "handicap to start out with, like health=450,max-health=450")
+	turnMargin = flag.Duration("turn-margin", 50*time.Millisecond,
+		"how much of the turn timeout to hold back for talking to the server")
+
+	RunnerError = errors.NewClass("runner error")
+
+	// minTimeout is the least time the bot gets to answer each turn, however
+	// little of the turn timeout -turn-margin leaves.
+	minTimeout = 10 * time.Millisecond
+
+	logger = spacelog.GetLogger()
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr,
+			"usage: %s [flags] <executable> [args...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	spacelog.Setup("bot-runner", spacelog.SetupConfig{
+		Output: "stderr",
+		Format: "{{.Message}}"})
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	err := Main(flag.Arg(0), flag.Args()[1:]...)
+	if err != nil {
+		logger.Errore(err)
+		os.Exit(1)
+	}
+}
+
+// readLines sends every line r produces on the returned channel, closing it
+// once r is exhausted or closed.
+func readLines(r io.Reader) <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return lines
+}
+
+func Main(executable string, args ...string) (err error) {
+	cmd := exec.Command(executable, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return RunnerError.Wrap(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return RunnerError.Wrap(err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return RunnerError.Wrap(err)
+	}
+	err = cmd.Start()
+	if err != nil {
+		return RunnerError.Wrap(err)
+	}
+	defer func() {
+		stdin.Close()
+		exited := make(chan error, 1)
+		go func() { exited <- cmd.Wait() }()
+		select {
+		case err := <-exited:
+			logger.Noticef("%s exited: %v", executable, err)
+		case <-time.After(time.Second):
+			logger.Noticef("%s didn't exit; killing it", executable)
+			cmd.Process.Kill()
+			<-exited
+		}
+	}()
+
+	go func() {
+		for line := range readLines(stderr) {
+			logger.Noticef("[%s] %s", executable, line)
+		}
+	}()
+	commands := readLines(stdout)
+
+	logger.Noticef("connecting to %q", *host)
+	c := client.New()
+	c.Team = *team
+	c.Handicap = *handicap
+	session, state, err := c.Join(*host, *gameName, *moniker)
+	if err != nil {
+		return err
+	}
+	var timeout time.Duration
+	if state.Config != nil {
+		timeout = botTimeout(time.Duration(state.Config.TurnTimeout),
+			*turnMargin)
+	}
+
+	encoder := json.NewEncoder(stdin)
+	reader := &commandReader{lines: commands}
+	for {
+		err = encoder.Encode(state)
+		if err != nil {
+			return RunnerError.Wrap(err)
+		}
+		if state.Status != game.Running {
+			logger.Noticef("game over: %s", state.Status)
+			return nil
+		}
+
+		command, ok := reader.next(timeout)
+		if !ok {
+			return RunnerError.New("%s closed its stdout", executable)
+		}
+
+		state, err = session.Send(command)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// botTimeout returns how long to wait for the bot's answer each turn, leaving
+// margin of turn_timeout for talking to the server, but never less than
+// minTimeout. It returns 0, for no limit, if turn_timeout is.
+func botTimeout(turn_timeout, margin time.Duration) time.Duration {
+	if turn_timeout <= 0 {
+		return 0
+	}
+	timeout := turn_timeout - margin
+	if timeout < minTimeout {
+		logger.Warnf("-turn-margin %s leaves too little of the %s turn "+
+			"timeout; giving the bot %s", margin, turn_timeout, minTimeout)
+		return minTimeout
+	}
+	return timeout
+}
+
+// commandReader reads the bot's answer for each turn from lines.
+type commandReader struct {
+	lines <-chan string
+	// late counts answers we gave up waiting for, so that we can throw them
+	// away when they finally arrive instead of using them for the wrong turn.
+	late int
+}
+
+// next waits up to timeout, or forever if timeout is 0, for the bot's answer
+// to the current turn. It returns a noop if the bot doesn't answer in time
+// or answers with something that isn't a command, and false if the bot has
+// stopped answering altogether.
+func (r *commandReader) next(timeout time.Duration) (game.Command, bool) {
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		deadline = time.After(timeout)
+	}
+	for {
+		select {
+		case line, ok := <-r.lines:
+			if !ok {
+				return game.Noop, false
+			}
+			if r.late > 0 {
+				r.late--
+				logger.Noticef("discarding late command %q", line)
+				continue
+			}
+			command, err := game.CommandFromString(strings.TrimSpace(line))
+			if err != nil || command == game.Join {
+				logger.Errorf("invalid command %q; sending noop", line)
+				return game.Noop, true
+			}
+			return command, true
+		case <-deadline:
+			logger.Noticef("no command within %s; sending noop", timeout)
+			r.late++
+			return game.Noop, true
+		}
+	}
+}
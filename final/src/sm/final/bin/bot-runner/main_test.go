@@ -0,0 +1,65 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"sm/final/game"
+)
+
+func TestBotTimeout(t *testing.T) {
+	for _, test := range []struct {
+		turn_timeout, margin, expected time.Duration
+	}{
+		{0, 50 * time.Millisecond, 0},
+		{time.Second / 2, 50 * time.Millisecond, 450 * time.Millisecond},
+		{time.Second / 2, time.Second / 2, minTimeout},
+		{time.Second / 2, time.Second, minTimeout},
+	} {
+		timeout := botTimeout(test.turn_timeout, test.margin)
+		if timeout != test.expected {
+			t.Errorf("%s with a %s margin: expected %s, got %s",
+				test.turn_timeout, test.margin, test.expected, timeout)
+		}
+	}
+}
+
+func TestCommandReaderDiscardsLateAnswers(t *testing.T) {
+	lines := make(chan string, 4)
+	reader := &commandReader{lines: lines}
+
+	// the bot misses two turns
+	for i := 0; i < 2; i++ {
+		command, ok := reader.next(time.Millisecond)
+		if !ok || command != game.Noop {
+			t.Fatalf("expected a noop for a missed turn, got %v", command)
+		}
+	}
+
+	// the answers to the missed turns finally show up, followed by the
+	// answer to this one
+	lines <- "left"
+	lines <- "fire"
+	lines <- "right"
+	command, ok := reader.next(time.Second)
+	if !ok || command != game.RotateRight {
+		t.Fatalf("expected right, got %v", command)
+	}
+	if reader.late != 0 {
+		t.Fatalf("expected no more late answers, got %d", reader.late)
+	}
+
+	lines <- "jump"
+	command, ok = reader.next(0)
+	if !ok || command != game.Noop {
+		t.Fatalf("expected a noop for an invalid command, got %v", command)
+	}
+
+	close(lines)
+	_, ok = reader.next(0)
+	if ok {
+		t.Fatalf("expected the bot to be gone")
+	}
+}
@@ -3,7 +3,9 @@
 package main
 
 import (
+	"crypto/tls"
 	"flag"
+	"net"
 	"net/http"
 
 	"github.com/jtolds/go-oauth2http/utils"
@@ -12,6 +14,7 @@ import (
 
 	"sm/codecomp/setup/general"
 	"sm/final/assets"
+	"sm/final/bot"
 	"sm/final/game"
 	"sm/final/renderer/sdl"
 	"sm/final/server"
@@ -21,6 +24,9 @@ var (
 	endpoint        = flag.String("http.endpoint", ":8080", "host:port to serve from")
 	backgroundMusic = flag.Bool("bgmusic", true, "if false, no background music")
 	staticPath      = flag.String("http.static", "", "path to file serving")
+	tcpEndpoint     = flag.String("tcp.endpoint", "", "host:port to serve the line protocol from, if set")
+	tcpCert         = flag.String("tcp.tls-cert", "", "certificate file, if the line protocol should use tls")
+	tcpKey          = flag.String("tcp.tls-key", "", "key file, if the line protocol should use tls")
 	logger          = spacelog.GetLogger()
 )
 
@@ -37,9 +43,50 @@ func docs(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(footer))
 }
 
+func listenLines() (net.Listener, error) {
+	if *tcpCert == "" && *tcpKey == "" {
+		return net.Listen("tcp", *tcpEndpoint)
+	}
+	cert, err := tls.LoadX509KeyPair(*tcpCert, *tcpKey)
+	if err != nil {
+		return nil, err
+	}
+	return tls.Listen("tcp", *tcpEndpoint, &tls.Config{
+		Certificates: []tls.Certificate{cert}})
+}
+
 func Main() error {
 	logger.Noticef("listening at %q", *endpoint)
 
+	config := game.DefaultConfig()
+	_, err := game.ParseHandicaps(config.Handicaps)
+	if err != nil {
+		return err
+	}
+	games := game.NewGames(config)
+	if config.MapDir != "" {
+		pool, err := game.LoadMapPool(config.MapDir, config.MapOrder,
+			config.MapWeights)
+		if err != nil {
+			return err
+		}
+		logger.Noticef("playing on maps %v", pool.Names())
+		games.SetMapPool(pool)
+	}
+	game_server := server.New(games)
+	game_server.SetOpponents(bot.Names(), bot.Attach)
+
+	if *tcpEndpoint != "" {
+		l, err := listenLines()
+		if err != nil {
+			return err
+		}
+		logger.Noticef("serving line protocol at %q", *tcpEndpoint)
+		go func() {
+			panic(server.NewLineServer(games).Serve(l))
+		}()
+	}
+
 	sdl.Run(func() {
 		if *backgroundMusic {
 			go func() {
@@ -52,7 +99,7 @@ func Main() error {
 
 		go func() {
 			mux := utils.DirMux{
-				"game": server.New(game.NewGames(game.DefaultConfig())),
+				"game": game_server,
 				"":     http.HandlerFunc(docs)}
 			if *staticPath != "" {
 				mux["static"] = http.FileServer(http.Dir(*staticPath))
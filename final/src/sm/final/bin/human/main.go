@@ -18,6 +18,9 @@ var (
 	host     = flag.String("host", "localhost:8080", "host to connect to")
 	gameName = flag.String("game", "yay:screen", "game name")
 	moniker  = flag.String("moniker", "human", "moniker to use")
+	useWS    = flag.Bool("websocket", false, "play over a websocket")
+	team     = flag.Int("team", 0, "team to join on, or 0 for whichever the game picks")
+	handicap = flag.String("handicap", "", "handicap to start out with, like health=450,max-health=450")
 
 	logger = spacelog.GetLogger()
 )
@@ -37,10 +40,17 @@ func main() {
 func Main() (err error) {
 	logger.Noticef("connecting to %q", *host)
 
-	client := client.New()
+	c := client.New()
+	c.Team = *team
+	c.Handicap = *handicap
 
 	user := make(chan string)
-	session, _, err := client.Join(*host, *gameName, *moniker)
+	var session client.Commander
+	if *useWS {
+		session, _, err = c.JoinWebSocket(*host, *gameName, *moniker)
+	} else {
+		session, _, err = c.Join(*host, *gameName, *moniker)
+	}
 	if err != nil {
 		return err
 	}
@@ -65,6 +75,20 @@ func Main() (err error) {
 				state, err = session.MoveForward()
 			case "f", "fire":
 				state, err = session.FireLaser()
+			case "s", "shield":
+				state, err = session.Shield()
+			case "c", "charge":
+				state, err = session.Send(game.ChargedShot)
+			case "spread":
+				state, err = session.Send(game.SpreadShot)
+			case "mine":
+				state, err = session.Send(game.DropMine)
+			case "b", "reverse":
+				state, err = session.Send(game.Reverse)
+			case "strafe-left":
+				state, err = session.Send(game.StrafeLeft)
+			case "strafe-right":
+				state, err = session.Send(game.StrafeRight)
 			default:
 				logger.Errorf("unknown command %q", command)
 				continue
@@ -0,0 +1,104 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"sm/final/game"
+	"sm/final/grid"
+)
+
+var (
+	minFairness = flag.Float64("min-fairness", 0.6,
+		"lowest spawn fairness (0-1) that doesn't get a warning")
+	verbose = flag.Bool("v", false, "print the score of every spawn")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [flags] <map>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	laser_distance := game.DefaultConfig().LaserLifetime
+	failed := false
+	for _, path := range flag.Args() {
+		if !check(path, laser_distance) {
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// check prints what it finds out about the map at path, and says whether
+// the map is free of problems.
+func check(path string, laser_distance int) bool {
+	m, err := grid.LoadMap(path)
+	if err != nil {
+		fmt.Printf("%s: %v\n\n", path, err)
+		return false
+	}
+	a := m.Analyze(laser_distance)
+
+	fmt.Printf("%s: %dx%d, %d open cells\n", path, a.Width, a.Height,
+		a.OpenCells)
+	if m.Name != "" || m.Author != "" {
+		fmt.Printf("  %q by %s\n", m.Name, m.Author)
+	}
+	if len(m.Spawns) > 0 {
+		fmt.Printf("  %d spawns\n", len(m.Spawns))
+	}
+	if len(m.BatterySpawners)+len(m.BatteryZones) > 0 {
+		fmt.Printf("  %d battery spawners, %d battery zone cells\n",
+			len(m.BatterySpawners), len(m.BatteryZones))
+	}
+	if a.Connected() {
+		fmt.Printf("  connected\n")
+	} else {
+		fmt.Printf("  %d separate areas\n", len(a.Components))
+	}
+	for _, c := range a.Symmetries {
+		if c.Holds() {
+			fmt.Printf("  symmetric: %s\n", c.Symmetry)
+		}
+	}
+	min_fire, max_fire := -1, 0
+	for _, score := range a.Spawns {
+		if min_fire < 0 || score.LinesOfFire < min_fire {
+			min_fire = score.LinesOfFire
+		}
+		if score.LinesOfFire > max_fire {
+			max_fire = score.LinesOfFire
+		}
+		if *verbose {
+			fmt.Printf("    %s: %d from a wall, %d lines of fire\n",
+				score.Coord, score.WallDistance, score.LinesOfFire)
+		}
+	}
+	fmt.Printf("  spawn fairness %.2f (%d to %d lines of fire)\n", a.Fairness,
+		min_fire, max_fire)
+	fmt.Printf("  %d dead ends\n", len(a.DeadEnds))
+
+	warnings := a.Warnings(*minFairness)
+	if m.Preset != "" {
+		if _, ok := game.Presets[m.Preset]; !ok {
+			warnings = append(warnings, fmt.Sprintf("unknown preset %q",
+				m.Preset))
+		}
+	}
+	for _, warning := range warnings {
+		fmt.Printf("  WARNING: %s\n", warning)
+	}
+	fmt.Println()
+	return len(warnings) == 0
+}
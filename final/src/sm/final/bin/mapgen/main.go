@@ -0,0 +1,69 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"sm/final/grid"
+)
+
+var (
+	width     = flag.Int("width", 16, "width of the map")
+	height    = flag.Int("height", 16, "height of the map")
+	algorithm = flag.String("algorithm", "segments", "segments, rooms or caves")
+	symmetry  = flag.String("symmetry", "",
+		"none, left-right, top-bottom, half-turn, quarter-turn or four-way "+
+			"(default depends on -players)")
+	players  = flag.Int("players", 2, "number of players the map is for")
+	density  = flag.Float64("density", 0.2, "fraction of the map that is walls")
+	enclosed = flag.Bool("enclosed", false, "put a wall around the map")
+	bounded  = flag.Bool("bounded", false, "make a map that doesn't wrap around")
+	seed     = flag.Int64("seed", 0, "seed to generate the map from, or 0 for a random one")
+	output   = flag.String("o", "", "file to write the map to, or stdout if empty")
+)
+
+func main() {
+	flag.Parse()
+	err := Main()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
+
+func Main() error {
+	opts := grid.Options{
+		Width:     *width,
+		Height:    *height,
+		Algorithm: grid.Algorithm(*algorithm),
+		Density:   *density,
+		Enclosed:  *enclosed,
+		Bounded:   *bounded,
+		Players:   *players,
+		Seed:      *seed,
+	}
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+		fmt.Fprintf(os.Stderr, "seed %d\n", opts.Seed)
+	}
+	if *symmetry != "" {
+		symmetries, err := grid.ParseSymmetry(*symmetry)
+		if err != nil {
+			return err
+		}
+		opts.Symmetries = append([]grid.Symmetry{}, symmetries...)
+	}
+
+	g, err := grid.Generate(opts)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		return g.WriteMap(os.Stdout)
+	}
+	return g.SaveToFile(*output)
+}
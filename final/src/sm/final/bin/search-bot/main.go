@@ -0,0 +1,37 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"sm/final/bot"
+)
+
+var (
+	game = flag.String("game", "http://localhost:8080/game/yay:screen",
+		"game basepath")
+	moniker   = flag.String("moniker", "search-bot", "moniker")
+	timeShare = flag.Float64("time-share", 0.5,
+		"fraction of the turn timeout to spend searching")
+	maxDepth = flag.Int("max-depth", 4, "how many turns ahead to look at most")
+	caution  = flag.Float64("caution", 0.2,
+		"weight of the opponent's best reply over its average one (0-1)")
+)
+
+func main() {
+	flag.Parse()
+
+	b := bot.NewSearch()
+	b.TimeShare = *timeShare
+	b.MaxDepth = *maxDepth
+	b.Caution = *caution
+
+	err := bot.PlayRemote(*game, *moniker, b)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package bot
+
+import (
+	"math/rand"
+	"time"
+
+	"sm/final/client"
+	"sm/final/game"
+	"sm/final/grid"
+)
+
+// Battery goes after the closest battery, or a random spot if there aren't
+// any, and shoots the other tank whenever it has a clear shot.
+type Battery struct {
+	rand   *rand.Rand
+	config *game.GameConfig
+	plan   *grid.Coord
+}
+
+func NewBattery() *Battery {
+	return &Battery{
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (b *Battery) Decide(state game.TurnState) game.Command {
+	board, err := client.NewBoard(state, b.config)
+	if err != nil {
+		logger.Errore(err)
+		return game.Noop
+	}
+	b.config = board.Config
+	if len(board.Opponents) == 0 {
+		return game.Noop
+	}
+	if b.plan != nil && *b.plan == board.Me {
+		b.plan = nil
+	}
+
+	if b.config == nil || state.Energy >= b.config.LaserEnergy {
+		for _, opponent := range board.Opponents {
+			if o, ok := board.AimAt(board.Me, opponent); ok {
+				return client.Face(board.Orientation, o, game.FireLaser)
+			}
+		}
+	}
+
+	// head for the battery that takes the fewest turns to get to
+	field := board.DistanceField(board.Pose(), board.Blocked())
+	best := -1
+	var target grid.Coord
+	for _, battery := range board.Batteries {
+		turns := field[battery.Y][battery.X]
+		if turns >= 0 && (best < 0 || turns < best) {
+			best, target = turns, battery
+		}
+	}
+	if best >= 0 {
+		b.plan = nil
+		if command, ok := board.NextStep(target); ok {
+			return command
+		}
+	}
+
+	if b.plan == nil {
+		var reachable []grid.Coord
+		for y, row := range field {
+			for x, turns := range row {
+				if turns > 0 {
+					reachable = append(reachable, grid.Coord{X: x, Y: y})
+				}
+			}
+		}
+		if len(reachable) == 0 {
+			return game.Noop
+		}
+		plan := reachable[b.rand.Intn(len(reachable))]
+		b.plan = &plan
+	}
+	command, ok := board.NextStep(*b.plan)
+	if !ok {
+		// somebody's in the way now; pick somewhere else next turn
+		b.plan = nil
+	}
+	return command
+}
@@ -0,0 +1,118 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package bot
+
+import (
+	"net/url"
+	"path"
+	"sort"
+
+	"github.com/spacemonkeygo/errors"
+	"github.com/spacemonkeygo/spacelog"
+
+	"sm/final/client"
+	"sm/final/game"
+)
+
+var (
+	BotError = errors.NewClass("bot error")
+
+	logger = spacelog.GetLogger()
+
+	bots = map[string]func() Bot{
+		"circle-bot":  func() Bot { return NewCircle() },
+		"battery-bot": func() Bot { return NewBattery() },
+		"search-bot":  func() Bot { return NewSearch() },
+	}
+)
+
+// Bot decides what a player does next. Decide is called once per turn with
+// the state the game server sent back, starting with the join state that
+// carries the game config.
+type Bot interface {
+	Decide(state game.TurnState) game.Command
+}
+
+// New returns a fresh instance of the built-in bot with the given name.
+func New(name string) (Bot, error) {
+	constructor, ok := bots[name]
+	if !ok {
+		return nil, BotError.New("no such bot %q", name)
+	}
+	return constructor(), nil
+}
+
+// Names returns the names of all the built-in bots.
+func Names() (names []string) {
+	for name := range bots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Play joins g as moniker and lets b take every turn until the game is over.
+func Play(g *game.Game, moniker string, b Bot) error {
+	id, state, err := g.Join(moniker)
+	if err != nil {
+		return err
+	}
+	return play(g, id, state, b)
+}
+
+func play(g *game.Game, id string, state game.TurnState, b Bot) (
+	err error) {
+	for state.Status == game.Running {
+		state, err = g.TakeTurn(id, b.Decide(state))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Attach adds the built-in bot with the given name as a player in g. It
+// returns once the bot has joined, without waiting for the game to start.
+func Attach(g *game.Game, name string) error {
+	b, err := New(name)
+	if err != nil {
+		return err
+	}
+	seat, err := g.TakeSeat(name, game.JoinOptions{})
+	if err != nil {
+		return err
+	}
+	go func() {
+		logger.Errore(play(g, seat.Id, seat.Wait(), b))
+	}()
+	return nil
+}
+
+// PlayRemote joins the game at game_url (like
+// http://localhost:8080/game/yay:screen) as moniker and lets b take every
+// turn until the game is over.
+func PlayRemote(game_url, moniker string, b Bot) error {
+	u, err := url.Parse(game_url)
+	if err != nil {
+		return BotError.Wrap(err)
+	}
+	session, state, err := client.New().Join(u.Host, path.Base(u.Path),
+		moniker)
+	if err != nil {
+		return err
+	}
+	return PlaySession(session, state, b)
+}
+
+// PlaySession lets b take every turn of an already joined session, starting
+// from the join state, until the game is over.
+func PlaySession(session client.Commander, state game.TurnState,
+	b Bot) (err error) {
+	for state.Status == game.Running {
+		state, err = session.Send(b.Decide(state))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package bot
+
+import (
+	"math/rand"
+	"time"
+
+	"sm/final/game"
+)
+
+// Circle drives around in circles, firing every so often.
+type Circle struct {
+	rand *rand.Rand
+	plan []game.Command
+}
+
+func NewCircle() *Circle {
+	return &Circle{
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (c *Circle) Decide(state game.TurnState) game.Command {
+	if len(c.plan) == 0 {
+		c.plan = append(c.plan, game.RotateRight)
+		if c.rand.Intn(4) == 0 {
+			c.plan = append(c.plan, game.FireLaser)
+		}
+		if c.rand.Intn(2) == 0 {
+			c.plan = append(c.plan, game.MoveForward, game.MoveForward)
+		}
+	}
+	command := c.plan[0]
+	c.plan = c.plan[1:]
+	return command
+}
@@ -0,0 +1,410 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package bot
+
+import (
+	"math/rand"
+
+	"sm/final/client"
+	"sm/final/game"
+	"sm/final/grid"
+)
+
+const me = grid.Owner(1)
+
+// model rebuilds a full game.Snapshot out of the turn states one player gets
+// to see. A turn state doesn't say which way the other tanks or the lasers
+// are heading, or how much health and energy the other tanks have left, so
+// model fills that in by running its previous snapshot forward with the
+// same rules the server uses and matching the result up against the new
+// state.
+type model struct {
+	config  *game.GameConfig
+	sim     *game.Config
+	rand    *rand.Rand
+	last    *game.Snapshot
+	action  game.Command
+	terrain *grid.Grid
+}
+
+func newModel(rng *rand.Rand) *model {
+	return &model{rand: rng}
+}
+
+// simConfig returns the rules to simulate a game with the given join config.
+// Battery spawns can't be predicted, so the simulation has none.
+func simConfig(config *game.GameConfig) *game.Config {
+	return &game.Config{
+		TurnTicks:       config.TurnTicks,
+		MaxPlayerHealth: config.MaxHealth,
+		MaxPlayerEnergy: config.MaxEnergy,
+		HealthLoss:      config.HealthLoss,
+		LaserDamage:     config.LaserDamage,
+		LaserLifetime:   config.LaserDistance,
+		LaserEnergy:     config.LaserEnergy,
+		BatteryPower:    config.BatteryPower,
+		BatteryHealth:   config.BatteryHealth,
+		EnergyCells:     simPickup(config.EnergyCells),
+		RepairKits:      simPickup(config.RepairKits),
+		DoubleDamage:    simPickup(config.DoubleDamage),
+		Speed:           simPickup(config.Speed),
+		ShieldEnergy:    config.ShieldEnergy,
+		ShieldTicks:     config.ShieldTicks,
+		ShieldFront:     config.ShieldFront,
+		Charge:          config.Charge,
+		ChargeEnergy:    config.ChargeEnergy,
+		ChargeDamage:    config.ChargeDamage,
+		ChargeDistance:  config.ChargeDistance,
+		Spread:          config.Spread,
+		SpreadEnergy:    config.SpreadEnergy,
+		Mines:           config.Mines,
+		MineEnergy:      config.MineEnergy,
+		MineDamage:      config.MineDamage,
+		MaxMines:        config.MaxMines,
+		ShotHeat:        config.ShotHeat,
+		MaxHeat:         config.MaxHeat,
+		HeatLoss:        config.HeatLoss,
+		OverheatTicks:   config.OverheatTicks,
+		Reverse:         config.Reverse,
+		ReverseEnergy:   config.ReverseEnergy,
+		Strafe:          config.Strafe,
+		StrafeEnergy:    config.StrafeEnergy,
+		BrickHealth:     config.BrickHealth,
+		LavaDamage:      config.LavaDamage,
+		Teams:           config.Teams,
+		FriendlyFire:    config.FriendlyFire,
+	}
+}
+
+// simPickup returns what picking up a pickup configured by pickup does,
+// without any of them spawning.
+func simPickup(pickup game.PickupConfig) game.PickupConfig {
+	return game.PickupConfig{Amount: pickup.Amount}
+}
+
+// update returns the snapshot that best explains state, with our own tank
+// as owner 1 and the others numbered from 2 up.
+func (m *model) update(state game.TurnState, board *client.Board) (
+	*game.Snapshot, error) {
+	if m.sim == nil {
+		if board.Config == nil {
+			return nil, BotError.New("no game config yet")
+		}
+		m.config = board.Config
+		m.sim = simConfig(board.Config)
+	}
+
+	m.terrain = m.learnTerrain(board)
+	snapshot := &game.Snapshot{
+		Grid: board.Grid.Clone(),
+		Map:  &grid.Map{Grid: m.terrain},
+	}
+	snapshot.Players = append(snapshot.Players, &game.Player{
+		Moniker:      "me",
+		Owner:        me,
+		Health:       state.Health,
+		Energy:       state.Energy,
+		Shield:       state.Shield,
+		Heat:         state.Heat,
+		Cooldown:     state.Cooldown,
+		DoubleDamage: state.PowerUps.DoubleDamage,
+		Speed:        state.PowerUps.Speed,
+		Stuck:        state.Stuck,
+		Coord:        board.Me,
+		Orientation:  board.Orientation,
+	})
+	for _, battery := range board.Batteries {
+		snapshot.Batteries = append(snapshot.Batteries,
+			game.Pickup{Coord: battery, Kind: board.CellAt(battery).Type})
+	}
+
+	predicted := m.predict(state, board)
+	if predicted != nil {
+		snapshot.Turn = predicted.Turn
+	}
+	for i, coord := range board.Opponents {
+		opponent := &game.Player{
+			Moniker: "opponent",
+			Owner:   grid.Owner(i + 2),
+			// everybody starts out like we did, facing north
+			Health:      state.Health,
+			Energy:      state.Energy,
+			Coord:       coord,
+			Orientation: grid.North,
+		}
+		if predicted != nil {
+			if guess := predicted.FindPlayer(opponent.Owner); guess != nil {
+				opponent.Health = guess.Health
+				opponent.Energy = guess.Energy
+				opponent.Orientation = guess.Orientation
+			}
+			if opponent.Health < 1 {
+				// we can still see it, so it isn't dead yet
+				opponent.Health = 1
+			}
+		}
+		snapshot.Players = append(snapshot.Players, opponent)
+	}
+	// we can't tell allies apart any better than opponents, but all that
+	// matters about them is that they are on our team
+	if len(board.Allies) > 0 {
+		snapshot.FindPlayer(me).Team = 1
+	}
+	for i, coord := range board.Allies {
+		snapshot.Players = append(snapshot.Players, &game.Player{
+			Moniker:     "ally",
+			Owner:       grid.Owner(len(board.Opponents) + i + 2),
+			Health:      state.Health,
+			Energy:      state.Energy,
+			Coord:       coord,
+			Orientation: grid.North,
+			Team:        1,
+		})
+	}
+
+	for _, coord := range board.Lasers {
+		laser := m.identifyLaser(snapshot, predicted, coord)
+		laser.Charged = board.CellAt(coord).Type == grid.Charge
+		snapshot.Lasers = append(snapshot.Lasers, laser)
+	}
+	// only other tanks' mines can hurt us, and we can't tell whose they are
+	for _, coord := range board.Mines {
+		snapshot.Mines = append(snapshot.Mines,
+			game.Mine{Coord: coord, Owner: grid.Other})
+	}
+	return snapshot, nil
+}
+
+// learnTerrain returns the hazards underneath board, as far as we know them.
+// The grid only shows a hazard while nothing is on it, so cells with a tank,
+// laser, mine or pickup on them keep whatever we last saw there.
+func (m *model) learnTerrain(board *client.Board) *grid.Grid {
+	terrain := board.Grid.Clone()
+	known := m.terrain
+	if known != nil && (known.Width() != terrain.Width() ||
+		known.Height() != terrain.Height()) {
+		known = nil
+	}
+	for y, row := range board.Grid.Cells() {
+		for x, cell := range row {
+			switch {
+			case cell.Type == grid.Player, cell.Type == grid.Laser,
+				cell.Type == grid.Charge, cell.Type == grid.Mine,
+				cell.Pickup():
+			default:
+				continue
+			}
+			coord := grid.Coord{X: x, Y: y}
+			terrain.SetCell(coord, grid.EmptyCell)
+			if known != nil && known.CellAt(coord).Hazard() {
+				terrain.SetCell(coord, known.CellAt(coord))
+			}
+		}
+	}
+	return terrain
+}
+
+// decided records the snapshot a decision was made on and what we did, so
+// the next update can tell what happened since.
+func (m *model) decided(snapshot *game.Snapshot, action game.Command) {
+	m.last, m.action = snapshot, action
+}
+
+// predict runs the last snapshot forward, working out what the other tanks
+// most likely did. Opponents are matched up with the closest tank of the
+// last snapshot, keeping their owners stable between turns. Then, one
+// opponent at a time, every action in every direction is tried, and the one
+// whose outcome looks most like state wins. Turning in place can't be seen,
+// so trying every direction covers it. When several guesses fit equally
+// well, the one that leaves the opponent with the most health wins, so we
+// never count on a shot that might not have landed.
+func (m *model) predict(state game.TurnState,
+	board *client.Board) *game.Snapshot {
+	if m.last == nil {
+		return nil
+	}
+	last := m.last.Clone()
+	actions := map[grid.Owner]game.Command{me: m.action}
+
+	var matched []*game.Player
+	taken := map[grid.Owner]bool{}
+	mine := last.FindPlayer(me)
+	for _, coord := range board.Opponents {
+		var closest *game.Player
+		for _, player := range last.Players {
+			if player.Owner == me || taken[player.Owner] || !player.Alive() ||
+				mine.Ally(player) {
+				continue
+			}
+			if closest == nil || board.Distance(player.Coord, coord) <
+				board.Distance(closest.Coord, coord) {
+				closest = player
+			}
+		}
+		if closest != nil {
+			taken[closest.Owner] = true
+		}
+		matched = append(matched, closest)
+	}
+
+	for _, player := range matched {
+		if player == nil {
+			continue
+		}
+		believed := player.Orientation
+		best_orientation, best_action := believed, game.Noop
+		best_mismatch, best_health := -1, 0
+		for o := grid.North; o <= grid.West; o++ {
+			for _, action := range []game.Command{game.Noop, game.MoveForward,
+				game.FireLaser} {
+				player.Orientation = o
+				actions[player.Owner] = action
+				predicted, _ := last.Next(m.sim, actions, m.rand)
+				mismatch := m.mismatch(state, board, predicted)
+				health := predicted.FindPlayer(player.Owner).Health
+				if best_mismatch < 0 || mismatch < best_mismatch ||
+					(mismatch == best_mismatch && health > best_health) ||
+					(mismatch == best_mismatch && health == best_health &&
+						o == believed && best_orientation != believed) {
+					best_orientation, best_action = o, action
+					best_mismatch, best_health = mismatch, health
+				}
+			}
+		}
+		player.Orientation = best_orientation
+		actions[player.Owner] = best_action
+	}
+
+	predicted, _ := last.Next(m.sim, actions, m.rand)
+
+	// renumber the opponents the way they show up on the board
+	renumbered := []*game.Player{predicted.FindPlayer(me)}
+	for i, player := range matched {
+		if player == nil {
+			continue
+		}
+		guess := predicted.FindPlayer(player.Owner)
+		guess.Owner = grid.Owner(i + 2)
+		guess.Coord = board.Opponents[i]
+		renumbered = append(renumbered, guess)
+	}
+	predicted.Players = renumbered
+	for _, laser := range predicted.Lasers {
+		for i, player := range matched {
+			if player != nil && laser.Owner == player.Owner {
+				laser.Owner = grid.Owner(i + 2)
+			}
+		}
+	}
+	return predicted
+}
+
+// mismatch scores how far predicted is from what state shows. Only the
+// things state actually shows count: our own tank, where the other tanks
+// are, and where the lasers are.
+func (m *model) mismatch(state game.TurnState, board *client.Board,
+	predicted *game.Snapshot) (mismatch int) {
+	mine := predicted.FindPlayer(me)
+	mismatch += abs(mine.Health-state.Health) + abs(mine.Energy-state.Energy)
+	if mine.Coord != board.Me {
+		mismatch += 100
+	}
+
+	seen := map[grid.Coord]bool{}
+	for _, coord := range board.Opponents {
+		seen[coord] = true
+	}
+	for _, player := range predicted.Players {
+		if player.Owner == me || !player.Alive() || mine.Ally(player) {
+			continue
+		}
+		if seen[player.Coord] {
+			delete(seen, player.Coord)
+		} else {
+			mismatch += 100
+		}
+	}
+	mismatch += 100 * len(seen)
+
+	lasers := map[grid.Coord]bool{}
+	for _, coord := range board.Lasers {
+		lasers[coord] = true
+	}
+	for _, laser := range predicted.Lasers {
+		if lasers[laser.Coord] {
+			delete(lasers, laser.Coord)
+		} else {
+			mismatch += 10
+		}
+	}
+	mismatch += 10 * len(lasers)
+	return mismatch
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// identifyLaser works out the laser at coord. Lasers that were already
+// around are where the prediction put them. A new one was fired this turn,
+// so it is a turn's worth of cells in front of whichever tank fired it.
+func (m *model) identifyLaser(snapshot, predicted *game.Snapshot,
+	coord grid.Coord) *game.Laser {
+	if predicted != nil {
+		for _, laser := range predicted.Lasers {
+			if laser.Coord == coord {
+				laser_copy := *laser
+				return &laser_copy
+			}
+		}
+	}
+
+	ticks := m.sim.TurnTicks
+	if ticks < 1 {
+		ticks = 1
+	}
+	for o := grid.North; o <= grid.West; o++ {
+		back := coord
+		for i := 0; i < ticks; i++ {
+			back = snapshot.Grid.RelativeTo(back, o.Opposite())
+			for _, player := range snapshot.Players {
+				if player.Coord != back || player.Owner == me {
+					continue
+				}
+				player.Orientation = o
+				player.Energy -= m.config.LaserEnergy
+				if player.Energy < 0 {
+					player.Energy = 0
+				}
+				return &game.Laser{
+					Coord:       coord,
+					Owner:       player.Owner,
+					Orientation: o,
+					Lifetime:    m.sim.LaserLifetime + 1 - ticks,
+				}
+			}
+		}
+	}
+
+	// no idea where it came from; assume the worst
+	me_coord := snapshot.Players[0].Coord
+	o := grid.North
+	dx, dy := snapshot.Grid.Offset(coord, me_coord)
+	switch {
+	case dx == 0 && dy > 0:
+		o = grid.South
+	case dy == 0 && dx < 0:
+		o = grid.West
+	case dy == 0 && dx > 0:
+		o = grid.East
+	}
+	return &game.Laser{
+		Coord:       coord,
+		Owner:       grid.Other,
+		Orientation: o,
+		Lifetime:    m.sim.LaserLifetime,
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package bot
+
+import (
+	"math/rand"
+	"testing"
+
+	"sm/final/client"
+	"sm/final/game"
+	"sm/final/grid"
+)
+
+// update has m take in what player 1 of s gets told.
+func update(t *testing.T, m *model, s *game.Snapshot,
+	config *game.Config) *game.Snapshot {
+	state := turnState(s, config)
+	board, err := client.NewBoard(state, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := m.update(state, board)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return snapshot
+}
+
+func TestModelHazards(t *testing.T) {
+	s, config := newTestGame(t, "_____", "__~__", "__^__", "____v")
+	rng := rand.New(rand.NewSource(1))
+	m := newModel(rng)
+	lava := grid.Coord{X: 2, Y: 1}
+
+	// drive onto the lava, which hides it
+	snapshot := update(t, m, s, config)
+	m.decided(snapshot, game.MoveForward)
+	s, _ = s.Next(config, map[grid.Owner]game.Command{1: game.MoveForward},
+		rng)
+	snapshot = update(t, m, s, config)
+	if cell := snapshot.Grid.CellAt(lava); cell.Type != grid.Player {
+		t.Fatalf("expected our tank on the lava, got %s", cell.Type)
+	}
+
+	// the model still knows it is there
+	actions := map[grid.Owner]game.Command{me: game.Noop}
+	next, _ := snapshot.Next(m.sim, actions, rng)
+	if health := next.FindPlayer(me).Health; health !=
+		s.FindPlayer(1).Health-config.HealthLoss-config.LavaDamage {
+		t.Fatalf("expected the lava to burn, but health went from %d to %d",
+			s.FindPlayer(1).Health, health)
+	}
+	actions[me] = game.MoveForward
+	next, _ = snapshot.Next(m.sim, actions, rng)
+	if cell := next.Grid.CellAt(lava); cell.Type != grid.Lava {
+		t.Fatalf("expected lava after driving off, got %s", cell.Type)
+	}
+}
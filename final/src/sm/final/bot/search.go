@@ -0,0 +1,320 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package bot
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"sm/final/client"
+	"sm/final/game"
+	"sm/final/grid"
+)
+
+var searchCommands = []game.Command{
+	game.Noop,
+	game.MoveForward,
+	game.RotateLeft,
+	game.RotateRight,
+	game.FireLaser,
+}
+
+// Search looks a few turns ahead with the game's own rules before deciding
+// what to do. Each of its moves is scored against every reply of the closest
+// opponent, weighing the average outcome against the worst one, and the
+// search goes one turn deeper at a time until it runs out of time, so it
+// always has an answer ready when the turn is up.
+type Search struct {
+	// TimeShare is how much of the turn timeout the search may use, leaving
+	// the rest for getting the command to the server.
+	TimeShare float64
+	// MaxDepth is how many turns ahead the search looks at most. It is the
+	// only limit when the game has no turn timeout.
+	MaxDepth int
+	// Caution is how much weight the worst reply gets over the average one,
+	// from 0 to 1.
+	Caution float64
+
+	rand      *rand.Rand
+	config    *game.GameConfig
+	budget    *client.TurnBudget
+	model     *model
+	batteries [][]int
+}
+
+func NewSearch() *Search {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return &Search{
+		TimeShare: 0.5,
+		MaxDepth:  4,
+		Caution:   0.2,
+		rand:      rng,
+		model:     newModel(rng),
+	}
+}
+
+func (s *Search) Decide(state game.TurnState) game.Command {
+	if s.budget != nil {
+		s.budget.Start()
+	}
+	board, err := client.NewBoard(state, s.config)
+	if err != nil {
+		logger.Errore(err)
+		return game.Noop
+	}
+	if s.budget == nil {
+		s.budget = client.NewTurnBudget(board.Config)
+	}
+	s.config = board.Config
+
+	snapshot, err := s.model.update(state, board)
+	if err != nil {
+		logger.Errore(err)
+		return game.Noop
+	}
+	s.batteries = batteryDistances(snapshot)
+
+	var deadline time.Time
+	if !s.budget.Unlimited() {
+		deadline = time.Now().Add(time.Duration(
+			float64(s.budget.Remaining()) * s.TimeShare))
+	}
+
+	// every pass searches the best command of the last one first, and only
+	// replaces it with one that is strictly better, so ties go to whatever
+	// pays off soonest
+	ranked := s.options(snapshot, me)
+	depth := 1
+	for ; depth <= s.MaxDepth; depth++ {
+		reranked, ok := s.rank(snapshot, ranked, depth, deadline)
+		if !ok {
+			break
+		}
+		ranked = reranked
+	}
+	command := ranked[0]
+	logger.Debugf("turn %d: %s after searching %d turns ahead",
+		snapshot.Turn, command, depth-1)
+	s.model.decided(snapshot, command)
+	return command
+}
+
+// rank returns commands sorted from best to worst searching depth turns
+// ahead, or false if the deadline passed first. Commands that are just
+// as good keep their order.
+func (s *Search) rank(snapshot *game.Snapshot, commands []game.Command,
+	depth int, deadline time.Time) (ranked []game.Command, ok bool) {
+	values := map[game.Command]float64{}
+	for _, command := range commands {
+		value, ok := s.expect(snapshot, command, depth, deadline)
+		if !ok {
+			return nil, false
+		}
+		values[command] = value
+	}
+	ranked = append(ranked, commands...)
+	for i := 1; i < len(ranked); i++ {
+		for j := i; j > 0 && values[ranked[j]] > values[ranked[j-1]]; j-- {
+			ranked[j], ranked[j-1] = ranked[j-1], ranked[j]
+		}
+	}
+	return ranked, true
+}
+
+// value is how good snapshot is for us with depth turns left to search.
+func (s *Search) value(snapshot *game.Snapshot, depth int,
+	deadline time.Time) (float64, bool) {
+	mine := snapshot.FindPlayer(me)
+	if depth == 0 || snapshot.Done() || mine == nil || !mine.Alive() {
+		return s.evaluate(snapshot), true
+	}
+	best := math.Inf(-1)
+	for _, command := range s.options(snapshot, me) {
+		value, ok := s.expect(snapshot, command, depth, deadline)
+		if !ok {
+			return 0, false
+		}
+		best = math.Max(best, value)
+	}
+	return best, true
+}
+
+// expect is how good doing mine is, over every reply the closest opponent
+// could make. Everybody else is assumed to sit still.
+func (s *Search) expect(snapshot *game.Snapshot, mine game.Command,
+	depth int, deadline time.Time) (float64, bool) {
+	actions := map[grid.Owner]game.Command{me: mine}
+	replies := []game.Command{game.Noop}
+	opponent := s.closestOpponent(snapshot)
+	if opponent != nil {
+		replies = s.options(snapshot, opponent.Owner)
+	}
+
+	total, worst := 0.0, math.Inf(1)
+	for _, theirs := range replies {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return 0, false
+		}
+		if opponent != nil {
+			actions[opponent.Owner] = theirs
+		}
+		next, _ := snapshot.Next(s.model.sim, actions, s.rand)
+		value, ok := s.value(next, depth-1, deadline)
+		if !ok {
+			return 0, false
+		}
+		total += value
+		worst = math.Min(worst, value)
+	}
+	average := total / float64(len(replies))
+	return (1-s.Caution)*average + s.Caution*worst, true
+}
+
+// options returns the commands worth trying for the player owned by owner.
+// Firing without the energy for it is just a noop, so it isn't tried.
+func (s *Search) options(snapshot *game.Snapshot,
+	owner grid.Owner) []game.Command {
+	player := snapshot.FindPlayer(owner)
+	if player == nil || player.Energy >= s.config.LaserEnergy {
+		return searchCommands
+	}
+	return searchCommands[:len(searchCommands)-1]
+}
+
+func (s *Search) closestOpponent(snapshot *game.Snapshot) (
+	closest *game.Player) {
+	mine := snapshot.FindPlayer(me)
+	if mine == nil {
+		return nil
+	}
+	best := -1
+	for _, player := range snapshot.Players {
+		if player.Owner == me || !player.Alive() {
+			continue
+		}
+		distance := snapshot.Grid.Distance(mine.Coord, player.Coord)
+		if best < 0 || distance < best {
+			closest, best = player, distance
+		}
+	}
+	return closest
+}
+
+// evaluate scores snapshot from our point of view: health difference first,
+// then energy, how far the closest battery is, having a shot lined up and
+// not being in the way of one.
+func (s *Search) evaluate(snapshot *game.Snapshot) float64 {
+	mine := snapshot.FindPlayer(me)
+	if mine == nil || !mine.Alive() {
+		if snapshot.AliveCount() == 0 {
+			return -5000
+		}
+		return -10000
+	}
+	if snapshot.Done() {
+		return 10000
+	}
+
+	damage := float64(s.config.LaserDamage)
+	// it takes a turn to face somebody and another for the laser to cover
+	// this many cells
+	closeRange := 2 * s.model.sim.TurnTicks
+
+	score := float64(mine.Health) + 8*float64(mine.Energy)
+	for _, player := range snapshot.Players {
+		if player.Owner == me || !player.Alive() || mine.Ally(player) {
+			continue
+		}
+		score -= float64(player.Health)
+		if mine.Energy >= s.config.LaserEnergy && s.reach(snapshot,
+			mine.Coord, mine.Orientation, player.Coord) > 0 {
+			score += damage / 4
+		}
+		if player.Energy < s.config.LaserEnergy {
+			continue
+		}
+		// we can't always tell which way it is facing, and it only takes
+		// a turn to face us anyway
+		for o := grid.North; o <= grid.West; o++ {
+			cells := s.reach(snapshot, player.Coord, o, mine.Coord)
+			switch {
+			case cells <= 0:
+			case o == player.Orientation:
+				score -= damage / 2
+			case cells <= closeRange:
+				score -= damage / 4
+			}
+		}
+	}
+	for _, laser := range snapshot.Lasers {
+		if laser.Owner != me && s.reach(snapshot, laser.Coord,
+			laser.Orientation, mine.Coord) > 0 {
+			score -= damage * 0.8
+		}
+		if laser.Owner != me {
+			continue
+		}
+		for _, player := range snapshot.Players {
+			if player.Owner != me && player.Alive() && s.reach(snapshot,
+				laser.Coord, laser.Orientation, player.Coord) > 0 {
+				score += damage / 4
+			}
+		}
+	}
+
+	if distance := s.batteries[mine.Coord.Y][mine.Coord.X]; distance >= 0 {
+		score -= 3 * float64(distance)
+	}
+	return score
+}
+
+// reach returns how many cells a laser going from from towards o covers
+// before it reaches target, or 0 if it runs into a wall or fizzles out
+// first.
+func (s *Search) reach(snapshot *game.Snapshot, from grid.Coord,
+	o grid.Orientation, target grid.Coord) int {
+	coord := from
+	for i := 0; i < s.config.LaserDistance; i++ {
+		var cell grid.Cell
+		var ok bool
+		cell, coord, o, ok = snapshot.Grid.LaserStep(coord, o)
+		if !ok || cell.Solid() {
+			return 0
+		}
+		if coord == target {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// batteryDistances returns, for every cell indexed [y][x], how many cells a
+// tank there has to drive to get to the closest battery, or -1 if there are
+// no batteries it can get to.
+func batteryDistances(snapshot *game.Snapshot) [][]int {
+	g := snapshot.Grid
+	distances := make([][]int, g.Height())
+	for y := range distances {
+		distances[y] = make([]int, g.Width())
+		for x := range distances[y] {
+			distances[y][x] = -1
+		}
+	}
+	var queue []grid.Coord
+	for _, battery := range snapshot.Batteries {
+		distances[battery.Coord.Y][battery.Coord.X] = 0
+		queue = append(queue, battery.Coord)
+	}
+	for len(queue) > 0 {
+		coord := queue[0]
+		queue = queue[1:]
+		for _, next := range g.Neighbors(coord) {
+			if distances[next.Y][next.X] < 0 && g.Passable(next) {
+				distances[next.Y][next.X] = distances[coord.Y][coord.X] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+	return distances
+}
@@ -0,0 +1,76 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"sm/final/game"
+	"sm/final/grid"
+)
+
+// newTestGame returns a game on the map in rows, with a player on every
+// spawn point, along with the rules it is played by.
+func newTestGame(t *testing.T, rows ...string) (*game.Snapshot,
+	*game.Config) {
+	config := game.DefaultConfig()
+	config.TurnTimeout = 0
+	config.TurnTicks = 1
+	m, err := grid.ReadMap(strings.NewReader(strings.Join(rows, "\n") + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := game.NewMapSnapshot(m)
+	for range m.Spawns {
+		_, err := s.AddPlayer(config, "player", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s, config
+}
+
+// turnState returns what player 1 of s gets told, as if it had just joined.
+func turnState(s *game.Snapshot, config *game.Config) game.TurnState {
+	state := s.TurnState(1)
+	state.Config = config.GameConfig()
+	return state
+}
+
+func TestSearchFiresWhenLinedUp(t *testing.T) {
+	s, config := newTestGame(t, ">___^")
+	search := NewSearch()
+	search.MaxDepth = 2
+	command := search.Decide(turnState(s, config))
+	if command != game.FireLaser {
+		t.Fatalf("expected %s, got %s", game.FireLaser, command)
+	}
+}
+
+func TestSearchStopsWhenDead(t *testing.T) {
+	s, config := newTestGame(t, ">___^", "_____", "____<")
+	search := NewSearch()
+	search.MaxDepth = 1
+	search.Decide(turnState(s, config))
+	snapshot := search.model.last.Clone()
+
+	// with a deadline that passed already, going any deeper fails
+	passed := time.Now().Add(-time.Second)
+	snapshot.FindPlayer(me).Health = 0
+	value, ok := search.value(snapshot, 3, passed)
+	if !ok || value != -10000 {
+		t.Fatalf("expected -10000 right away, got %v, %v", value, ok)
+	}
+
+	// losing track of our own tank altogether is just as bad
+	snapshot.Players = snapshot.Players[1:]
+	if opponent := search.closestOpponent(snapshot); opponent != nil {
+		t.Fatalf("expected no closest opponent, got %s", opponent)
+	}
+	value, ok = search.value(snapshot, 3, passed)
+	if !ok || value != -10000 {
+		t.Fatalf("expected -10000 right away, got %v, %v", value, ok)
+	}
+}
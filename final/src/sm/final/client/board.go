@@ -0,0 +1,190 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package client
+
+import (
+	"sm/final/game"
+	"sm/final/grid"
+)
+
+// Board is a player's view of the game, parsed out of a TurnState.
+type Board struct {
+	*grid.Grid
+	Config      *game.GameConfig
+	Me          grid.Coord
+	Orientation grid.Orientation
+	Opponents   []grid.Coord
+	// Allies are the tanks on our team, if there are teams.
+	Allies []grid.Coord
+	// Batteries includes every other kind of pickup.
+	Batteries []grid.Coord
+	// Lasers includes charged shots.
+	Lasers []grid.Coord
+	// Mines are the mines other tanks have dropped. Our own can't hurt us.
+	Mines []grid.Coord
+}
+
+// NewBoard parses state into a Board. The game config is only sent with the
+// join state, so callers should hang on to it and pass it in for every later
+// state.
+func NewBoard(state game.TurnState, config *game.GameConfig) (*Board, error) {
+	if state.Config != nil {
+		config = state.Config
+	}
+	// we are always owner 1 in our own view of the grid
+	parsed, err := grid.Deserialize(state.Grid, grid.Owner(1))
+	if err != nil {
+		return nil, ClientError.Wrap(err)
+	}
+	if config != nil {
+		parsed.SetBounded(config.Bounded)
+	}
+	b := &Board{
+		Grid:        parsed,
+		Config:      config,
+		Orientation: state.Orientation,
+	}
+	found := false
+	for y, row := range parsed.Cells() {
+		for x, cell := range row {
+			coord := grid.Coord{X: x, Y: y}
+			switch cell.Type {
+			case grid.Player:
+				switch cell.Owner {
+				case grid.Other:
+					b.Opponents = append(b.Opponents, coord)
+				case grid.Ally:
+					b.Allies = append(b.Allies, coord)
+				default:
+					b.Me, found = coord, true
+				}
+			case grid.Battery, grid.EnergyCell, grid.RepairKit,
+				grid.DoubleDamage, grid.Speed:
+				b.Batteries = append(b.Batteries, coord)
+			case grid.Laser, grid.Charge:
+				b.Lasers = append(b.Lasers, coord)
+			case grid.Mine:
+				if cell.Owner == grid.Other {
+					b.Mines = append(b.Mines, coord)
+				}
+			}
+		}
+	}
+	if !found {
+		return nil, ClientError.New("own tank is not on the grid")
+	}
+	return b, nil
+}
+
+// Closest returns whichever of coords is the fewest moves away from from.
+func (b *Board) Closest(from grid.Coord, coords []grid.Coord) (
+	closest grid.Coord, ok bool) {
+	best := -1
+	for _, coord := range coords {
+		if d := b.Distance(from, coord); best < 0 || d < best {
+			closest, best = coord, d
+		}
+	}
+	return closest, best >= 0
+}
+
+// LineOfFire returns the cells a laser fired from from towards o would pass
+// through, wrapping around the board unless it is bounded and bouncing off
+// mirrors, until it reaches the laser distance, runs into a wall or the
+// edge, or hits a tank, pickup or mine. If it hits something, that cell is
+// the last one returned.
+func (b *Board) LineOfFire(from grid.Coord, o grid.Orientation) (
+	cells []grid.Coord) {
+	distance := b.Width() * b.Height()
+	if b.Config != nil {
+		distance = b.Config.LaserDistance
+	}
+	coord := from
+	for i := 0; i < distance; i++ {
+		var cell grid.Cell
+		var ok bool
+		cell, coord, o, ok = b.LaserStep(coord, o)
+		if !ok || cell.Solid() {
+			break
+		}
+		cells = append(cells, coord)
+		if cell.Type == grid.Player || cell.Pickup() ||
+			cell.Type == grid.Mine {
+			break
+		}
+	}
+	return cells
+}
+
+// CanHit says whether a laser fired from from towards o would hit target.
+func (b *Board) CanHit(from grid.Coord, o grid.Orientation,
+	target grid.Coord) bool {
+	cells := b.LineOfFire(from, o)
+	return len(cells) > 0 && cells[len(cells)-1] == target
+}
+
+// AimAt returns an orientation from which a laser fired from from would hit
+// target, if there is one.
+func (b *Board) AimAt(from, target grid.Coord) (o grid.Orientation,
+	ok bool) {
+	for o := grid.North; o <= grid.West; o++ {
+		if b.CanHit(from, o, target) {
+			return o, true
+		}
+	}
+	return grid.North, false
+}
+
+// Pose returns where our tank is and which way it is facing.
+func (b *Board) Pose() grid.Pose {
+	return grid.Pose{Coord: b.Me, Orientation: b.Orientation}
+}
+
+// Blocked returns the cells our tank can't drive onto besides walls: the
+// ones other tanks are on, and the ones with their mines.
+func (b *Board) Blocked() grid.Blocked {
+	blocked := grid.Blocked{}
+	for _, opponent := range b.Opponents {
+		blocked[opponent] = true
+	}
+	for _, ally := range b.Allies {
+		blocked[ally] = true
+	}
+	for _, mine := range b.Mines {
+		blocked[mine] = true
+	}
+	return blocked
+}
+
+// NextStep returns the command that starts our tank down the shortest path
+// to target, going around walls and other tanks.
+func (b *Board) NextStep(target grid.Coord) (command game.Command, ok bool) {
+	path, ok := b.ShortestPath(b.Pose(), target, b.Blocked())
+	if !ok {
+		return game.Noop, false
+	}
+	if len(path) == 0 {
+		return game.Noop, true
+	}
+	return ManeuverCommand(path[0]), true
+}
+
+// Directions returns the orientations that would bring a tank at from closer
+// to target, ignoring walls and taking any wrap-around into account.
+func (b *Board) Directions(from, target grid.Coord) (
+	options []grid.Orientation) {
+	dx, dy := b.Offset(from, target)
+	if dy < 0 {
+		options = append(options, grid.North)
+	}
+	if dy > 0 {
+		options = append(options, grid.South)
+	}
+	if dx < 0 {
+		options = append(options, grid.West)
+	}
+	if dx > 0 {
+		options = append(options, grid.East)
+	}
+	return options
+}
@@ -5,6 +5,7 @@ package client
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"sm/final/game"
 	"sm/final/server"
@@ -12,6 +13,11 @@ import (
 
 type Client struct {
 	http_client *http.Client
+	// Team is the team to ask to join on, or 0 to leave it up to the game.
+	Team int
+	// Handicap, if not empty, is the handicap to ask to start out with,
+	// like "health=450,max-health=450".
+	Handicap string
 }
 
 func New() *Client {
@@ -29,6 +35,12 @@ func (c *Client) Join(host, game, moniker string) (session *Session,
 		return nil, state, ClientError.Wrap(err)
 	}
 	req.Header.Set(server.PlayerMonikerHeader, moniker)
+	if c.Team != 0 {
+		req.Header.Set(server.PlayerTeamHeader, strconv.Itoa(c.Team))
+	}
+	if c.Handicap != "" {
+		req.Header.Set(server.PlayerHandicapHeader, c.Handicap)
+	}
 	resp, err := c.http_client.Do(req)
 	if err != nil {
 		return nil, state, ClientError.Wrap(err)
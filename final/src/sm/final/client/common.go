@@ -17,6 +17,23 @@ var (
 	ServerError = errors.NewClass("server error")
 )
 
+// Commander is the set of turns a player can take, whichever transport its
+// session uses.
+type Commander interface {
+	NoOp() (game.TurnState, error)
+	RotateLeft() (game.TurnState, error)
+	RotateRight() (game.TurnState, error)
+	MoveForward() (game.TurnState, error)
+	FireLaser() (game.TurnState, error)
+	Shield() (game.TurnState, error)
+	Send(command game.Command) (game.TurnState, error)
+}
+
+var (
+	_ Commander = (*Session)(nil)
+	_ Commander = (*WebSocketSession)(nil)
+)
+
 func errBody(r io.Reader) string {
 	var msg [256]byte
 	n, _ := r.Read(msg[:])
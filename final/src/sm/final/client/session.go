@@ -25,26 +25,30 @@ func newSession(http_client *http.Client, url, player_id string) *Session {
 }
 
 func (s *Session) NoOp() (state game.TurnState, err error) {
-	return s.sendCommand(game.Noop)
+	return s.Send(game.Noop)
 }
 
 func (s *Session) RotateLeft() (state game.TurnState, err error) {
-	return s.sendCommand(game.RotateLeft)
+	return s.Send(game.RotateLeft)
 }
 
 func (s *Session) RotateRight() (state game.TurnState, err error) {
-	return s.sendCommand(game.RotateRight)
+	return s.Send(game.RotateRight)
 }
 
 func (s *Session) MoveForward() (state game.TurnState, err error) {
-	return s.sendCommand(game.MoveForward)
+	return s.Send(game.MoveForward)
 }
 
 func (s *Session) FireLaser() (state game.TurnState, err error) {
-	return s.sendCommand(game.FireLaser)
+	return s.Send(game.FireLaser)
 }
 
-func (s *Session) sendCommand(command game.Command) (state game.TurnState,
+func (s *Session) Shield() (state game.TurnState, err error) {
+	return s.Send(game.Shield)
+}
+
+func (s *Session) Send(command game.Command) (state game.TurnState,
 	err error) {
 	url := fmt.Sprintf("%s/%s", s.url, command)
 	req, err := http.NewRequest("POST", url, nil)
@@ -0,0 +1,102 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package client
+
+import (
+	"math"
+	"time"
+
+	"sm/final/game"
+	"sm/final/grid"
+)
+
+// Rotations returns the shortest sequence of turns that takes a tank facing
+// from to facing to.
+func Rotations(from, to grid.Orientation) []game.Command {
+	switch to {
+	case from:
+		return nil
+	case from.Left():
+		return []game.Command{game.RotateLeft}
+	case from.Right():
+		return []game.Command{game.RotateRight}
+	default:
+		return []game.Command{game.RotateRight, game.RotateRight}
+	}
+}
+
+// Face returns the next command that turns a tank facing from towards to, or
+// then if it already faces that way.
+func Face(from, to grid.Orientation, then game.Command) game.Command {
+	if rotations := Rotations(from, to); len(rotations) > 0 {
+		return rotations[0]
+	}
+	return then
+}
+
+// ManeuverCommand returns the command that performs m.
+func ManeuverCommand(m grid.Maneuver) game.Command {
+	switch m {
+	case grid.TurnLeft:
+		return game.RotateLeft
+	case grid.TurnRight:
+		return game.RotateRight
+	default:
+		return game.MoveForward
+	}
+}
+
+// TurnBudget keeps track of how much of the turn timeout is left to decide
+// on an action.
+type TurnBudget struct {
+	timeout time.Duration
+	start   time.Time
+}
+
+// NewTurnBudget returns a budget for games with the given config. A nil
+// config, or one without a turn timeout, gives an unlimited budget.
+func NewTurnBudget(config *game.GameConfig) *TurnBudget {
+	b := &TurnBudget{start: time.Now()}
+	if config != nil {
+		b.timeout = time.Duration(config.TurnTimeout)
+	}
+	return b
+}
+
+// Start marks the beginning of a turn. It should be called as soon as a new
+// state arrives.
+func (b *TurnBudget) Start() {
+	b.start = time.Now()
+}
+
+// Unlimited says whether the game has no turn timeout.
+func (b *TurnBudget) Unlimited() bool {
+	return b.timeout <= 0
+}
+
+// Deadline returns when the current turn times out. It returns the zero time
+// for an unlimited budget.
+func (b *TurnBudget) Deadline() time.Time {
+	if b.Unlimited() {
+		return time.Time{}
+	}
+	return b.start.Add(b.timeout)
+}
+
+// Remaining returns how much of the current turn is left. It is never
+// negative, and is the longest possible duration for an unlimited budget.
+func (b *TurnBudget) Remaining() time.Duration {
+	if b.Unlimited() {
+		return time.Duration(math.MaxInt64)
+	}
+	remaining := b.Deadline().Sub(time.Now())
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// Expired says whether the current turn has already timed out.
+func (b *TurnBudget) Expired() bool {
+	return !b.Unlimited() && b.Remaining() == 0
+}
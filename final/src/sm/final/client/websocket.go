@@ -0,0 +1,113 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package client
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"golang.org/x/net/websocket"
+
+	"sm/final/game"
+	"sm/final/server"
+)
+
+// WebSocketSession plays a game over a single websocket connection instead
+// of an http request per turn. It has the same methods as Session.
+type WebSocketSession struct {
+	conn *websocket.Conn
+}
+
+func (c *Client) JoinWebSocket(host, game_name, moniker string) (
+	session *WebSocketSession, state game.TurnState, err error) {
+
+	ws_url := fmt.Sprintf("ws://%s/game/%s/%s?moniker=%s", host,
+		url.QueryEscape(game_name), server.WebSocketAction,
+		url.QueryEscape(moniker))
+	header := http.Header{}
+	header.Set(server.PlayerMonikerHeader, moniker)
+	if c.Team != 0 {
+		header.Set(server.PlayerTeamHeader, strconv.Itoa(c.Team))
+	}
+	if c.Handicap != "" {
+		header.Set(server.PlayerHandicapHeader, c.Handicap)
+	}
+	config, err := websocket.NewConfig(ws_url, fmt.Sprintf("http://%s/", host))
+	if err != nil {
+		return nil, state, ClientError.Wrap(err)
+	}
+	config.Header = header
+	conn, err := websocket.DialConfig(config)
+	if err != nil {
+		return nil, state, ServerError.New("Unable to join: %v", err)
+	}
+	session = &WebSocketSession{conn: conn}
+	state, err = session.readState()
+	if err != nil {
+		conn.Close()
+		return nil, state, err
+	}
+	return session, state, nil
+}
+
+func (s *WebSocketSession) NoOp() (state game.TurnState, err error) {
+	return s.Send(game.Noop)
+}
+
+func (s *WebSocketSession) RotateLeft() (state game.TurnState, err error) {
+	return s.Send(game.RotateLeft)
+}
+
+func (s *WebSocketSession) RotateRight() (state game.TurnState, err error) {
+	return s.Send(game.RotateRight)
+}
+
+func (s *WebSocketSession) MoveForward() (state game.TurnState, err error) {
+	return s.Send(game.MoveForward)
+}
+
+func (s *WebSocketSession) FireLaser() (state game.TurnState, err error) {
+	return s.Send(game.FireLaser)
+}
+
+func (s *WebSocketSession) Shield() (state game.TurnState, err error) {
+	return s.Send(game.Shield)
+}
+
+// Close hangs up the connection. The server treats a player that has hung up
+// like any other player that stops taking turns.
+func (s *WebSocketSession) Close() error {
+	return s.conn.Close()
+}
+
+func (s *WebSocketSession) Send(command game.Command) (
+	state game.TurnState, err error) {
+	err = websocket.JSON.Send(s.conn,
+		server.WebSocketCommand{Command: string(command)})
+	if err != nil {
+		return state, ClientError.Wrap(err)
+	}
+	return s.readState()
+}
+
+// readState reads the next state, or the error the server hung up with.
+func (s *WebSocketSession) readState() (state game.TurnState, err error) {
+	var msg struct {
+		game.TurnState
+		Error string `json:"error"`
+	}
+	err = websocket.JSON.Receive(s.conn, &msg)
+	if err == io.EOF {
+		return state, ServerError.New("connection closed")
+	}
+	if err != nil {
+		return state, ServerError.Wrap(err)
+	}
+	if msg.Error != "" {
+		return state, ServerError.New("%s", msg.Error)
+	}
+	return msg.TurnState, nil
+}
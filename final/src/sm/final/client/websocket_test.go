@@ -0,0 +1,83 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"sm/final/game"
+	"sm/final/server"
+)
+
+func newTestServer() *httptest.Server {
+	config := game.DefaultConfig()
+	config.NumPlayers = 2
+	config.Width, config.Height = 8, 8
+	config.GridFile, config.MapDir = "", ""
+	return httptest.NewServer(http.StripPrefix("/game",
+		server.New(game.NewGames(config))))
+}
+
+type joined struct {
+	session *WebSocketSession
+	state   game.TurnState
+	err     error
+}
+
+// joinWebSockets has every moniker join name at once, since nobody gets
+// their first state until everybody has joined.
+func joinWebSockets(t *testing.T, host, name string, monikers ...string) (
+	sessions []*WebSocketSession) {
+	results := make(chan joined)
+	for _, moniker := range monikers {
+		go func(moniker string) {
+			session, state, err := New().JoinWebSocket(host, name, moniker)
+			results <- joined{session: session, state: state, err: err}
+		}(moniker)
+	}
+	for range monikers {
+		result := <-results
+		if result.err != nil {
+			t.Fatal(result.err)
+		}
+		if result.state.Status != game.Running || result.state.Config == nil {
+			t.Fatalf("expected to join a running game, got %+v", result.state)
+		}
+		sessions = append(sessions, result.session)
+	}
+	return sessions
+}
+
+func TestWebSocketSession(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "http://")
+	sessions := joinWebSockets(t, host, "session", "a", "b")
+	defer sessions[0].Close()
+	defer sessions[1].Close()
+
+	// both players have to take their turn before either hears back
+	errs := make(chan error)
+	for _, session := range sessions {
+		go func(session *WebSocketSession) {
+			state, err := session.RotateLeft()
+			if err == nil && state.Status != game.Running {
+				err = ServerError.New("game is %s", state.Status)
+			}
+			errs <- err
+		}(session)
+	}
+	for range sessions {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, _, err := New().JoinWebSocket(host, "session", "c")
+	if err == nil || !strings.Contains(err.Error(), "only 2 players") {
+		t.Fatalf("expected joining a full game to fail, got %v", err)
+	}
+}
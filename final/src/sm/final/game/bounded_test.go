@@ -0,0 +1,44 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package game
+
+import (
+	"testing"
+
+	"sm/final/grid"
+)
+
+func TestTickBounded(t *testing.T) {
+	config := testConfig()
+	health, energy := config.PlayerHealth, config.PlayerEnergy
+	fire := []map[grid.Owner]Command{{1: FireLaser}, nil, nil, nil}
+	runTickTests(t, []tickTest{
+		{
+			name:  "tanks stop at the edge",
+			rows:  []string{"topology: bounded", "__^__", "_____", "____v"},
+			turns: []map[grid.Owner]Command{{1: MoveForward}},
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 2, 0, grid.North, health, energy)
+			},
+		},
+		{
+			name:  "lasers fizzle out at the edge",
+			rows:  []string{"topology: bounded", "<___<"},
+			turns: fire,
+			check: func(s *Snapshot, events []Event) string {
+				if len(s.Lasers) != 0 {
+					return "the laser is still around"
+				}
+				return expectHealth(s, 2, health)
+			},
+		},
+		{
+			name:  "but wrap around otherwise",
+			rows:  []string{"<___<"},
+			turns: fire,
+			check: func(s *Snapshot, events []Event) string {
+				return expectHealth(s, 2, health-config.LaserDamage)
+			},
+		},
+	})
+}
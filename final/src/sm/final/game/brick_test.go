@@ -0,0 +1,76 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package game
+
+import (
+	"fmt"
+	"testing"
+
+	"sm/final/grid"
+)
+
+func TestTickBricks(t *testing.T) {
+	config := testConfig()
+	health := config.PlayerHealth
+	brick := grid.Coord{X: 2, Y: 0}
+	two_shots := []map[grid.Owner]Command{{1: FireLaser}, {1: FireLaser},
+		nil, nil, nil}
+	runTickTests(t, []tickTest{
+		{
+			name: "bricks stop lasers until they are knocked out",
+			rows: []string{">_D_<"},
+			config: func(config *Config) {
+				config.BrickHealth = 2
+			},
+			turns: two_shots[:3],
+			check: func(s *Snapshot, events []Event) string {
+				if countEvents(events, BrickDestroyed) != 1 ||
+					s.Grid.CellAt(brick).Type != grid.Empty {
+					return "the brick is still there"
+				}
+				return expectHealth(s, 2, health)
+			},
+		},
+		{
+			name: "hits wear them down",
+			rows: []string{">_D_<"},
+			config: func(config *Config) {
+				config.BrickHealth = 3
+			},
+			turns: two_shots,
+			check: func(s *Snapshot, events []Event) string {
+				cell := s.Grid.CellAt(brick)
+				if cell.Type != grid.Brick || cell.Damage != 2 {
+					return fmt.Sprintf("expected a brick with 2 damage, got %v",
+						cell)
+				}
+				return expectHealth(s, 2, health)
+			},
+		},
+		{
+			name: "and they're walls with a brick health of 0",
+			rows: []string{">_D_<"},
+			config: func(config *Config) {
+				config.BrickHealth = 0
+			},
+			turns: two_shots,
+			check: func(s *Snapshot, events []Event) string {
+				if s.Grid.CellAt(brick).Type != grid.Brick {
+					return "the brick was knocked out"
+				}
+				return ""
+			},
+		},
+		{
+			name:  "tanks can't drive through them",
+			rows:  []string{">D__<"},
+			turns: []map[grid.Owner]Command{{1: MoveForward}},
+			check: func(s *Snapshot, events []Event) string {
+				if s.FindPlayer(1).Coord.X != 0 {
+					return "the tank drove through the brick"
+				}
+				return ""
+			},
+		},
+	})
+}
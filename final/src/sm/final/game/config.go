@@ -5,6 +5,8 @@ package game
 import (
 	"flag"
 	"time"
+
+	"sm/final/grid"
 )
 
 var (
@@ -12,14 +14,22 @@ var (
 	gridHeight         = flag.Int("logic.grid-height", 16, "height of grid")
 	wallCount          = flag.Int("logic.grid-walls", 8, "# of walls")
 	gridEnclosed       = flag.Bool("logic.grid-enclosed", false, "true if the grid should be enclosed")
+	gridBounded        = flag.Bool("logic.grid-bounded", false, "true if the grid shouldn't wrap around at the edges")
+	gridAlgorithm      = flag.String("logic.grid-algorithm", "", "generate grids with segments, rooms or caves instead of dropping logic.grid-walls walls")
+	gridDensity        = flag.Float64("logic.grid-density", 0.2, "fraction of a generated grid that is walls")
+	gridSymmetry       = flag.String("logic.grid-symmetry", "", "symmetry of generated grids: none, left-right, top-bottom, half-turn, quarter-turn or four-way (default depends on logic.players)")
+	gridSeed           = flag.Int64("logic.grid-seed", 0, "seed for generated grids, or 0 for a different grid every game")
 	turnTimeout        = flag.Duration("logic.turn-timeout", time.Second/2, "timeout before player action is ignored for the turn")
 	connectBackTimeout = flag.Duration("logic.connect-back-timeout", 10*time.Second, "timeout before we assume player has left the game")
 	numPlayers         = flag.Int("logic.players", 2, "number of players in a game")
+	numTeams           = flag.Int("logic.teams", 0, "how many teams players are split into, or 0 for every player for themselves")
+	friendlyFire       = flag.Bool("logic.friendly-fire", false, "true if lasers and mines hurt teammates")
 	turnTicks          = flag.Int("logic.turn-ticks", 2, "how many game ticks per player action")
 	playerHealth       = flag.Int("logic.player-health", 300, "starting player health")
 	maxPlayerHealth    = flag.Int("logic.player-max-health", 300, "max player health")
 	playerEnergy       = flag.Int("logic.player-energy", 5, "starting player energy")
 	maxPlayerEnergy    = flag.Int("logic.player-max-energy", 10, "max player energy")
+	handicaps          = flag.String("logic.handicaps", "", "semicolon separated moniker:handicap list of players that start out differently, like veteran:health=450,max-health=450;rookie:timeout=2s")
 	healthLoss         = flag.Int("logic.health-loss", 1, "health lost per turn")
 	laserDamage        = flag.Int("logic.laser-damage", 50, "amount of damage when hit by a laser")
 	laserLifetime      = flag.Int("logic.laser-distance", 32, "how many cells a laser travels")
@@ -28,7 +38,45 @@ var (
 	batteryHealth      = flag.Int("logic.battery-health", 20, "how much health a battery gives you")
 	batteryTicks       = flag.Int("logic.battery-ticks", 15, "ticks between battery pack spawn")
 	maxBatteries       = flag.Int("logic.max-batteries", 5, "the maximum number of batteries on the grid")
+	energyCellTicks    = flag.Int("logic.energy-cell-ticks", 0, "ticks between energy cell spawns, or 0 for none")
+	maxEnergyCells     = flag.Int("logic.max-energy-cells", 3, "the maximum number of energy cells on the grid")
+	energyCellPower    = flag.Int("logic.energy-cell-power", 5, "how much energy an energy cell gives you")
+	repairKitTicks     = flag.Int("logic.repair-kit-ticks", 0, "ticks between repair kit spawns, or 0 for none")
+	maxRepairKits      = flag.Int("logic.max-repair-kits", 2, "the maximum number of repair kits on the grid")
+	repairKitHealth    = flag.Int("logic.repair-kit-health", 40, "how much health a repair kit gives you")
+	doubleDamageTicks  = flag.Int("logic.double-damage-ticks", 0, "ticks between double damage power-up spawns, or 0 for none")
+	maxDoubleDamage    = flag.Int("logic.max-double-damage", 1, "the maximum number of double damage power-ups on the grid")
+	doubleDamageLength = flag.Int("logic.double-damage-length", 10, "how many ticks double damage lasts")
+	speedTicks         = flag.Int("logic.speed-ticks", 0, "ticks between speed power-up spawns, or 0 for none")
+	maxSpeed           = flag.Int("logic.max-speed", 1, "the maximum number of speed power-ups on the grid")
+	speedLength        = flag.Int("logic.speed-length", 10, "how many ticks speed lasts")
+	shieldEnergy       = flag.Int("logic.shield-energy", 3, "how much energy it takes to put up a shield")
+	shieldTicks        = flag.Int("logic.shield-ticks", 0, "how many ticks a shield stays up for, or 0 for no shields")
+	shieldFront        = flag.Bool("logic.shield-front", false, "true if shields only block lasers hitting the front of the tank")
+	charge             = flag.Bool("logic.charge", false, "true if tanks can fire charged shots")
+	chargeEnergy       = flag.Int("logic.charge-energy", 3, "how much energy a charged shot takes")
+	chargeDamage       = flag.Int("logic.charge-damage", 100, "amount of damage when hit by a charged shot")
+	chargeDistance     = flag.Int("logic.charge-distance", 48, "how many cells a charged shot travels")
+	spread             = flag.Bool("logic.spread", false, "true if tanks can fire spread shots")
+	spreadEnergy       = flag.Int("logic.spread-energy", 2, "how much energy a spread shot takes")
+	mines              = flag.Bool("logic.mines", false, "true if tanks can drop mines")
+	mineEnergy         = flag.Int("logic.mine-energy", 2, "how much energy dropping a mine takes")
+	mineDamage         = flag.Int("logic.mine-damage", 75, "amount of damage when driving onto a mine")
+	maxMines           = flag.Int("logic.max-mines", 3, "the maximum number of mines each player can have on the grid at once")
+	shotHeat           = flag.Int("logic.shot-heat", 0, "how much heat each laser fired adds, or 0 for no heat")
+	maxHeat            = flag.Int("logic.max-heat", 10, "how much heat it takes to overheat")
+	heatLoss           = flag.Int("logic.heat-loss", 1, "how much heat goes away each tick")
+	overheatTicks      = flag.Int("logic.overheat-ticks", 6, "how many ticks an overheated tank can't fire for")
+	reverse            = flag.Bool("logic.reverse", false, "true if tanks can back up")
+	reverseEnergy      = flag.Int("logic.reverse-energy", 0, "how much energy backing up takes")
+	strafe             = flag.Bool("logic.strafe", false, "true if tanks can strafe to either side")
+	strafeEnergy       = flag.Int("logic.strafe-energy", 1, "how much energy strafing takes")
+	lavaDamage         = flag.Int("logic.lava-damage", 10, "health lost per tick sitting in lava")
+	brickHealth        = flag.Int("logic.brick-health", 3, "how many laser hits it takes to knock out a brick, or 0 if they can't be")
 	gridFile           = flag.String("gridfile", "", "file containing grid to use")
+	mapDir             = flag.String("maps.dir", "", "directory of maps for games to pick from, instead of one gridfile")
+	mapOrder           = flag.String("maps.order", RandomOrder, "how games pick maps from -maps.dir: random or rotation")
+	mapWeights         = flag.String("maps.weights", "", "comma separated name=weight list of how often random maps come up; 1 if not listed")
 )
 
 type Config struct {
@@ -36,14 +84,22 @@ type Config struct {
 	Height             int
 	Walls              int
 	Enclosed           bool
+	Bounded            bool
+	Algorithm          string
+	Density            float64
+	Symmetry           string
+	Seed               int64
 	TurnTimeout        time.Duration
 	ConnectBackTimeout time.Duration
 	TurnTicks          int
 	NumPlayers         int
+	Teams              int
+	FriendlyFire       bool
 	PlayerHealth       int
 	MaxPlayerHealth    int
 	PlayerEnergy       int
 	MaxPlayerEnergy    int
+	Handicaps          string
 	HealthLoss         int
 	LaserDamage        int
 	LaserLifetime      int
@@ -52,7 +108,37 @@ type Config struct {
 	BatteryHealth      int
 	BatteryTicks       int
 	MaxBatteries       int
+	EnergyCells        PickupConfig
+	RepairKits         PickupConfig
+	DoubleDamage       PickupConfig
+	Speed              PickupConfig
+	ShieldEnergy       int
+	ShieldTicks        int
+	ShieldFront        bool
+	Charge             bool
+	ChargeEnergy       int
+	ChargeDamage       int
+	ChargeDistance     int
+	Spread             bool
+	SpreadEnergy       int
+	Mines              bool
+	MineEnergy         int
+	MineDamage         int
+	MaxMines           int
+	ShotHeat           int
+	MaxHeat            int
+	HeatLoss           int
+	OverheatTicks      int
+	Reverse            bool
+	ReverseEnergy      int
+	Strafe             bool
+	StrafeEnergy       int
+	BrickHealth        int
+	LavaDamage         int
 	GridFile           string
+	MapDir             string
+	MapOrder           string
+	MapWeights         string
 }
 
 func DefaultConfig() *Config {
@@ -61,14 +147,22 @@ func DefaultConfig() *Config {
 		Height:             *gridHeight,
 		Walls:              *wallCount,
 		Enclosed:           *gridEnclosed,
+		Bounded:            *gridBounded,
+		Algorithm:          *gridAlgorithm,
+		Density:            *gridDensity,
+		Symmetry:           *gridSymmetry,
+		Seed:               *gridSeed,
 		TurnTimeout:        *turnTimeout,
 		ConnectBackTimeout: *connectBackTimeout,
 		TurnTicks:          *turnTicks,
 		NumPlayers:         *numPlayers,
+		Teams:              *numTeams,
+		FriendlyFire:       *friendlyFire,
 		PlayerHealth:       *playerHealth,
 		MaxPlayerHealth:    *maxPlayerHealth,
 		PlayerEnergy:       *playerEnergy,
 		MaxPlayerEnergy:    *maxPlayerEnergy,
+		Handicaps:          *handicaps,
 		HealthLoss:         *healthLoss,
 		LaserDamage:        *laserDamage,
 		LaserLifetime:      *laserLifetime,
@@ -77,6 +171,164 @@ func DefaultConfig() *Config {
 		BatteryHealth:      *batteryHealth,
 		BatteryTicks:       *batteryTicks,
 		MaxBatteries:       *maxBatteries,
+		EnergyCells:        PickupConfig{Ticks: *energyCellTicks, Max: *maxEnergyCells, Amount: *energyCellPower},
+		RepairKits:         PickupConfig{Ticks: *repairKitTicks, Max: *maxRepairKits, Amount: *repairKitHealth},
+		DoubleDamage:       PickupConfig{Ticks: *doubleDamageTicks, Max: *maxDoubleDamage, Amount: *doubleDamageLength},
+		Speed:              PickupConfig{Ticks: *speedTicks, Max: *maxSpeed, Amount: *speedLength},
+		ShieldEnergy:       *shieldEnergy,
+		ShieldTicks:        *shieldTicks,
+		ShieldFront:        *shieldFront,
+		Charge:             *charge,
+		ChargeEnergy:       *chargeEnergy,
+		ChargeDamage:       *chargeDamage,
+		ChargeDistance:     *chargeDistance,
+		Spread:             *spread,
+		SpreadEnergy:       *spreadEnergy,
+		Mines:              *mines,
+		MineEnergy:         *mineEnergy,
+		MineDamage:         *mineDamage,
+		MaxMines:           *maxMines,
+		ShotHeat:           *shotHeat,
+		MaxHeat:            *maxHeat,
+		HeatLoss:           *heatLoss,
+		OverheatTicks:      *overheatTicks,
+		Reverse:            *reverse,
+		ReverseEnergy:      *reverseEnergy,
+		Strafe:             *strafe,
+		StrafeEnergy:       *strafeEnergy,
+		BrickHealth:        *brickHealth,
+		LavaDamage:         *lavaDamage,
 		GridFile:           *gridFile,
+		MapDir:             *mapDir,
+		MapOrder:           *mapOrder,
+		MapWeights:         *mapWeights,
+	}
+}
+
+// GameConfig returns the part of c that players get told about when they
+// join.
+func (c *Config) GameConfig() *GameConfig {
+	return &GameConfig{
+		TurnTimeout:        int64(c.TurnTimeout),
+		ConnectBackTimeout: int64(c.ConnectBackTimeout),
+		TurnTicks:          c.TurnTicks,
+		MaxHealth:          c.MaxPlayerHealth,
+		MaxEnergy:          c.MaxPlayerEnergy,
+		HealthLoss:         c.HealthLoss,
+		LaserDamage:        c.LaserDamage,
+		LaserDistance:      c.LaserLifetime,
+		LaserEnergy:        c.LaserEnergy,
+		BatteryPower:       c.BatteryPower,
+		BatteryHealth:      c.BatteryHealth,
+		EnergyCells:        c.EnergyCells,
+		RepairKits:         c.RepairKits,
+		DoubleDamage:       c.DoubleDamage,
+		Speed:              c.Speed,
+		ShieldEnergy:       c.ShieldEnergy,
+		ShieldTicks:        c.ShieldTicks,
+		ShieldFront:        c.ShieldFront,
+		Charge:             c.Charge,
+		ChargeEnergy:       c.ChargeEnergy,
+		ChargeDamage:       c.ChargeDamage,
+		ChargeDistance:     c.ChargeDistance,
+		Spread:             c.Spread,
+		SpreadEnergy:       c.SpreadEnergy,
+		Mines:              c.Mines,
+		MineEnergy:         c.MineEnergy,
+		MineDamage:         c.MineDamage,
+		MaxMines:           c.MaxMines,
+		ShotHeat:           c.ShotHeat,
+		MaxHeat:            c.MaxHeat,
+		HeatLoss:           c.HeatLoss,
+		OverheatTicks:      c.OverheatTicks,
+		Reverse:            c.Reverse,
+		ReverseEnergy:      c.ReverseEnergy,
+		Strafe:             c.Strafe,
+		StrafeEnergy:       c.StrafeEnergy,
+		BrickHealth:        c.BrickHealth,
+		LavaDamage:         c.LavaDamage,
+		Teams:              c.Teams,
+		FriendlyFire:       c.FriendlyFire,
+	}
+}
+
+// PlayerConfig is like GameConfig, but with player's own limits, which a
+// handicap might have changed.
+func (c *Config) PlayerConfig(player *Player) *GameConfig {
+	config := c.GameConfig()
+	config.TurnTimeout = int64(player.TurnTimeout)
+	config.MaxHealth = player.maxHealth(c)
+	config.MaxEnergy = player.maxEnergy(c)
+	return config
+}
+
+// PickupConfig is how one kind of pickup other than batteries shows up: a
+// new one every Ticks ticks (never, if Ticks is 0), as long as there are
+// fewer than Max of them around (or Max is -1). Amount is how much energy an
+// energy cell gives, how much health a repair kit gives, or how many ticks a
+// power-up lasts.
+type PickupConfig struct {
+	Ticks  int `json:"ticks"`
+	Max    int `json:"max"`
+	Amount int `json:"amount"`
+}
+
+// Pickup returns the config for pickups of type kind. ok is false for
+// batteries, which have config of their own, and for anything that isn't a
+// pickup.
+func (c *Config) Pickup(kind grid.Type) (pickup PickupConfig, ok bool) {
+	switch kind {
+	case grid.EnergyCell:
+		return c.EnergyCells, true
+	case grid.RepairKit:
+		return c.RepairKits, true
+	case grid.DoubleDamage:
+		return c.DoubleDamage, true
+	case grid.Speed:
+		return c.Speed, true
+	}
+	return pickup, false
+}
+
+// Preset changes a config to play by a particular set of rules. A map can
+// name the preset it is meant to be played with in its header.
+type Preset func(c *Config)
+
+var Presets = map[string]Preset{
+	// classic is the rules as set by flags
+	"classic": func(c *Config) {},
+	// quick games start out with less health and lose it faster
+	"quick": func(c *Config) {
+		c.PlayerHealth /= 2
+		c.HealthLoss *= 2
+	},
+	// scarce games only ever have one battery around, and not often
+	"scarce": func(c *Config) {
+		c.MaxBatteries = 1
+		c.BatteryTicks *= 2
+	},
+	// pickups games have energy cells and repair kits instead of batteries,
+	// and power-ups every so often
+	"pickups": func(c *Config) {
+		ticks := c.BatteryTicks
+		if ticks <= 0 {
+			ticks = 15
+		}
+		c.BatteryTicks = 0
+		c.EnergyCells.Ticks = ticks
+		c.RepairKits.Ticks = 2 * ticks
+		c.DoubleDamage.Ticks = 4 * ticks
+		c.Speed.Ticks = 4 * ticks
+	},
+}
+
+// WithPreset returns a copy of c changed by the preset called name.
+func (c *Config) WithPreset(name string) (*Config, error) {
+	preset, ok := Presets[name]
+	if !ok {
+		return nil, GameError.New("unknown preset %q", name)
 	}
+	copy := *c
+	preset(&copy)
+	return &copy, nil
 }
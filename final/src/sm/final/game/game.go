@@ -9,6 +9,7 @@ import (
 	std_errors "errors"
 	"fmt"
 	math_rand "math/rand"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -21,8 +22,10 @@ import (
 )
 
 var (
-	GameError = errors.NewClass("game error", errors.NoCaptureStack())
-	JoinError = GameError.NewClass("join error")
+	GameError     = errors.NewClass("game error", errors.NoCaptureStack())
+	JoinError     = GameError.NewClass("join error")
+	HandicapError = JoinError.NewClass("handicap error")
+	MapError      = GameError.NewClass("map error")
 
 	logger = spacelog.GetLogger()
 )
@@ -37,6 +40,13 @@ const (
 	RotateLeft   Command = "left"
 	RotateRight  Command = "right"
 	FireLaser    Command = "fire"
+	Shield       Command = "shield"
+	ChargedShot  Command = "charge"
+	SpreadShot   Command = "spread"
+	DropMine     Command = "mine"
+	Reverse      Command = "reverse"
+	StrafeLeft   Command = "strafe-left"
+	StrafeRight  Command = "strafe-right"
 )
 
 func (c Command) MarshalJSON() ([]byte, error) {
@@ -67,6 +77,20 @@ func CommandFromString(s string) (Command, error) {
 		return RotateRight, nil
 	case FireLaser:
 		return FireLaser, nil
+	case Shield:
+		return Shield, nil
+	case ChargedShot:
+		return ChargedShot, nil
+	case SpreadShot:
+		return SpreadShot, nil
+	case DropMine:
+		return DropMine, nil
+	case Reverse:
+		return Reverse, nil
+	case StrafeLeft:
+		return StrafeLeft, nil
+	case StrafeRight:
+		return StrafeRight, nil
 	}
 	return "", std_errors.New(fmt.Sprintf("%s is not a valid command", s))
 }
@@ -110,10 +134,22 @@ type TurnState struct {
 	Health      int              `json:"health"`
 	Energy      int              `json:"energy"`
 	Orientation grid.Orientation `json:"orientation"`
+	Shield      int              `json:"shield"`
+	Heat        int              `json:"heat"`
+	Cooldown    int              `json:"cooldown"`
+	Stuck       bool             `json:"stuck"`
+	Team        int              `json:"team"`
+	PowerUps    PowerUps         `json:"power_ups"`
 	Grid        string           `json:"grid"`
 	Config      *GameConfig      `json:"config,omitempty"`
 }
 
+// PowerUps is how many more ticks each of a tank's power-ups lasts.
+type PowerUps struct {
+	DoubleDamage int `json:"double_damage"`
+	Speed        int `json:"speed"`
+}
+
 type Player struct {
 	Id          string
 	Moniker     string
@@ -122,6 +158,29 @@ type Player struct {
 	Health      int
 	Energy      int
 	Coord       grid.Coord
+	// Shield is how many more ticks the tank's shield is up for.
+	Shield int
+	// Heat builds up as the tank fires, and Cooldown is how many more ticks
+	// it can't fire for after overheating.
+	Heat     int
+	Cooldown int
+	// DoubleDamage and Speed are how many more ticks the tank's power-ups
+	// last for.
+	DoubleDamage int
+	Speed        int
+	// Stuck tanks are on slow ground, and their next move only gets them
+	// going again.
+	Stuck bool
+	// Team is which team the tank is on, from 1 up, or 0 if there are no
+	// teams.
+	Team int
+	// MaxHealth and MaxEnergy are the tank's own limits, which are the
+	// config's unless it has a handicap. 0 means the config's.
+	MaxHealth int
+	MaxEnergy int
+	// TurnTimeout is how long the tank's player has each turn, or 0 for as
+	// long as they like.
+	TurnTimeout time.Duration
 }
 
 func (p *Player) String() string {
@@ -133,9 +192,70 @@ func (p *Player) ToCell() grid.Cell {
 		Type:        grid.Player,
 		Orientation: p.Orientation,
 		Owner:       p.Owner,
+		Shielded:    p.Shield > 0,
+	}
+}
+
+// Shielded says whether the tank's shield blocks a laser heading towards
+// heading when it hits.
+func (p *Player) Shielded(config *Config, heading grid.Orientation) bool {
+	if p.Shield <= 0 {
+		return false
+	}
+	return !config.ShieldFront || heading == p.Orientation.Opposite()
+}
+
+// Ally says whether other is on the same team as p. Nobody is their own
+// ally, and there are no allies without teams.
+func (p *Player) Ally(other *Player) bool {
+	return p.Team != 0 && p.Team == other.Team && p.Owner != other.Owner
+}
+
+// CanFire says whether the tank has cooled down enough to fire.
+func (p *Player) CanFire() bool {
+	return p.Cooldown <= 0
+}
+
+// collect gives p whatever battery gives, without going over the maximum
+// health or energy.
+// collect gives p whatever pickup gives. Energy never goes over the max.
+// Batteries have never capped health, so only repair kits do.
+func (p *Player) collect(config *Config, pickup Pickup) {
+	switch pickup.kind() {
+	case grid.Battery:
+		p.Energy += config.BatteryPower
+		p.Health += config.BatteryHealth
+	case grid.EnergyCell:
+		p.Energy += config.EnergyCells.Amount
+	case grid.RepairKit:
+		p.Health += config.RepairKits.Amount
+		if max_health := p.maxHealth(config); p.Health > max_health {
+			p.Health = max_health
+		}
+	case grid.DoubleDamage:
+		p.DoubleDamage = config.DoubleDamage.Amount
+	case grid.Speed:
+		p.Speed = config.Speed.Amount
+	}
+	if max_energy := p.maxEnergy(config); p.Energy > max_energy {
+		p.Energy = max_energy
 	}
 }
 
+func (p *Player) maxHealth(config *Config) int {
+	if p.MaxHealth > 0 {
+		return p.MaxHealth
+	}
+	return config.MaxPlayerHealth
+}
+
+func (p *Player) maxEnergy(config *Config) int {
+	if p.MaxEnergy > 0 {
+		return p.MaxEnergy
+	}
+	return config.MaxPlayerEnergy
+}
+
 func (p *Player) Alive() bool {
 	return p.Health > 0
 }
@@ -153,24 +273,68 @@ type Laser struct {
 	Owner       grid.Owner
 	Orientation grid.Orientation
 	Lifetime    int
+	// Charged lasers are charged shots.
+	Charged bool
+	// Doubled lasers were fired with double damage.
+	Doubled bool
 }
 
 func (l *Laser) ToCell() grid.Cell {
+	cell_type := grid.Laser
+	if l.Charged {
+		cell_type = grid.Charge
+	}
 	return grid.Cell{
-		Type:        grid.Laser,
+		Type:        cell_type,
 		Owner:       l.Owner,
 		Orientation: l.Orientation,
 	}
 }
 
-type Battery struct {
+// Damage returns how much damage l does to the tank it hits.
+func (l *Laser) Damage(config *Config) int {
+	damage := config.LaserDamage
+	if l.Charged {
+		damage = config.ChargeDamage
+	}
+	if l.Doubled {
+		damage *= 2
+	}
+	return damage
+}
+
+// Mine is a mine a tank dropped, waiting for another tank to drive onto it.
+type Mine struct {
+	Coord grid.Coord
+	Owner grid.Owner
+}
+
+func (m *Mine) ToCell() grid.Cell {
+	return grid.Cell{
+		Type:  grid.Mine,
+		Owner: m.Owner,
+	}
+}
+
+// Pickup is anything tanks pick up by driving onto it. Kind is which
+// kind of pickup it is: grid.Battery (or empty, for short), or one of
+// grid.EnergyCell, grid.RepairKit, grid.DoubleDamage or grid.Speed.
+type Pickup struct {
 	Coord grid.Coord
+	Kind  grid.Type
 }
 
-func (e *Battery) ToCell() grid.Cell {
+func (e *Pickup) ToCell() grid.Cell {
 	return grid.Cell{
-		Type: grid.Battery,
+		Type: e.kind(),
+	}
+}
+
+func (e *Pickup) kind() grid.Type {
+	if e.Kind == "" {
+		return grid.Battery
 	}
+	return e.Kind
 }
 
 type playerAction struct {
@@ -180,23 +344,27 @@ type playerAction struct {
 }
 
 type Game struct {
-	mtx        sync.Mutex
-	config     *Config
-	renderer   renderer.Renderer
-	turn       int
-	players    []*Player
-	grid       *grid.Grid
-	actionsch  chan playerAction
-	rand       *math_rand.Rand
-	lasers     []*Laser
-	explosions []grid.Coord
-	batteries  []Battery
-}
-
-func NewGame(config *Config, renderer renderer.Renderer, done_callback func()) *Game {
+	mtx       sync.Mutex
+	config    *Config
+	renderer  renderer.Renderer
+	state     *Snapshot
+	mapName   string
+	handicaps map[string]Handicap
+	actionsch chan playerAction
+	rand      *math_rand.Rand
+}
+
+// NewGame starts a game played by config's rules. It fails if config's
+// handicaps can't be parsed.
+func NewGame(config *Config, renderer renderer.Renderer,
+	done_callback func()) (*Game, error) {
 	if config == nil {
 		config = DefaultConfig()
 	}
+	handicaps, err := ParseHandicaps(config.Handicaps)
+	if err != nil {
+		return nil, err
+	}
 
 	logger.Noticef("new game: config=%+v", config)
 	g := &Game{
@@ -204,94 +372,289 @@ func NewGame(config *Config, renderer renderer.Renderer, done_callback func()) *
 		renderer:  renderer,
 		actionsch: make(chan playerAction),
 		rand:      math_rand.New(math_rand.NewSource(time.Now().UnixNano())),
+		handicaps: handicaps,
 	}
 
+	var the_map *grid.Map
 	if config.GridFile != "" {
 		var err error
-		g.grid, err = grid.LoadFromFile(config.GridFile)
+		the_map, err = grid.LoadMap(config.GridFile)
 		logger.Errore(err)
+		if err == nil {
+			g.mapName = strings.TrimSuffix(filepath.Base(config.GridFile),
+				".map")
+		}
 	}
 
-	if g.grid == nil {
-		g.grid = grid.NewRandom(config.Width, config.Height, config.Walls,
-			config.Enclosed)
+	if the_map == nil && config.Algorithm != "" {
+		the_grid, err := generateGrid(config)
+		logger.Errore(err)
+		if err == nil {
+			the_map = &grid.Map{Grid: the_grid}
+		}
 	}
 
+	if the_map == nil {
+		the_map = &grid.Map{Grid: grid.NewRandom(config.Width, config.Height,
+			config.Walls, config.Enclosed)}
+	}
+
+	if config.Bounded {
+		the_map.Grid.SetBounded(true)
+	}
+	if g.renderer != nil {
+		g.renderer.SetBounded(the_map.Grid.Bounded())
+	}
+
+	if the_map.Players > 0 && the_map.Players != config.NumPlayers {
+		logger.Warnf("map %q is meant for %d players, not %d", the_map.Name,
+			the_map.Players, config.NumPlayers)
+	}
+	if the_map.Preset != "" {
+		preset_config, err := config.WithPreset(the_map.Preset)
+		logger.Errore(err)
+		if err == nil {
+			logger.Noticef("playing with the %s preset: config=%+v",
+				the_map.Preset, preset_config)
+			g.config = preset_config
+		}
+	}
+	g.state = NewMapSnapshot(the_map)
+
 	go g.run(done_callback)
-	return g
+	return g, nil
+}
+
+func generateGrid(config *Config) (*grid.Grid, error) {
+	opts := grid.Options{
+		Width:     config.Width,
+		Height:    config.Height,
+		Algorithm: grid.Algorithm(config.Algorithm),
+		Density:   config.Density,
+		Enclosed:  config.Enclosed,
+		Bounded:   config.Bounded,
+		Players:   config.NumPlayers,
+		Seed:      config.Seed,
+	}
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+	if config.Symmetry != "" {
+		symmetries, err := grid.ParseSymmetry(config.Symmetry)
+		if err != nil {
+			return nil, err
+		}
+		// an explicit none still has to be told apart from the default
+		opts.Symmetries = append([]grid.Symmetry{}, symmetries...)
+	}
+	return grid.Generate(opts)
 }
 
 func (g *Game) Join(moniker string) (id string, state TurnState, err error) {
-	id, statech, err := g.join(moniker)
+	return g.JoinWith(moniker, JoinOptions{})
+}
+
+// JoinTeam joins the game on team, or on whichever team has the fewest
+// players if team is 0.
+func (g *Game) JoinTeam(moniker string, team int) (id string,
+	state TurnState, err error) {
+	return g.JoinWith(moniker, JoinOptions{Team: team})
+}
+
+// JoinOptions are the choices a player can make when joining a game.
+type JoinOptions struct {
+	// Team is the team to join on, or 0 for the one with the fewest players.
+	Team int
+	// Handicap, if not nil, is how the player starts out, instead of any
+	// handicap the game's config has for their moniker.
+	Handicap *Handicap
+}
+
+// JoinWith joins the game the way opts say. The config in state has the
+// player's own limits.
+func (g *Game) JoinWith(moniker string, opts JoinOptions) (id string,
+	state TurnState, err error) {
+	seat, err := g.TakeSeat(moniker, opts)
 	if err != nil {
-		return "", TurnState{}, nil
+		return "", TurnState{}, err
 	}
-	state = <-statech
-	state.Config = &GameConfig{
-		TurnTimeout:        int64(g.config.TurnTimeout),
-		ConnectBackTimeout: int64(g.config.ConnectBackTimeout),
-		MaxHealth:          g.config.MaxPlayerHealth,
-		MaxEnergy:          g.config.MaxPlayerEnergy,
-		HealthLoss:         g.config.HealthLoss,
-		LaserDamage:        g.config.LaserDamage,
-		LaserDistance:      g.config.LaserLifetime,
-		LaserEnergy:        g.config.LaserEnergy,
-		BatteryPower:       g.config.BatteryPower,
-		BatteryHealth:      g.config.BatteryHealth,
+	return seat.Id, seat.Wait(), nil
+}
+
+// Seat is a player that has joined a game that may not have started yet.
+type Seat struct {
+	Id      string
+	statech <-chan TurnState
+	config  *GameConfig
+}
+
+// TakeSeat joins the game the way opts say, like JoinWith, but returns
+// without waiting for the game to start.
+func (g *Game) TakeSeat(moniker string, opts JoinOptions) (*Seat, error) {
+	id, statech, config, err := g.join(moniker, opts)
+	if err != nil {
+		return nil, err
 	}
-	return id, state, nil
+	return &Seat{Id: id, statech: statech, config: config}, nil
+}
+
+// Wait waits for the game to start and returns the player's first state,
+// which has the player's own limits in its config.
+func (s *Seat) Wait() TurnState {
+	state := <-s.statech
+	state.Config = s.config
+	return state
+}
+
+// MapName returns the name of the map the game is played on, or "" if it
+// is played on a random grid.
+func (g *Game) MapName() string {
+	return g.mapName
+}
+
+// GameInfo is what game listings say about a game.
+type GameInfo struct {
+	Name    string   `json:"name"`
+	Map     string   `json:"map"`
+	Players []string `json:"players"`
+	Turn    int      `json:"turn"`
+	Status  string   `json:"status"`
+}
+
+func (g *Game) Info(name string) GameInfo {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	info := GameInfo{
+		Name:    name,
+		Map:     g.mapName,
+		Players: []string{},
+		Turn:    g.state.Turn,
+		Status:  "waiting",
+	}
+	for _, player := range g.state.Players {
+		info.Players = append(info.Players, player.Moniker)
+	}
+	switch {
+	case g.isStarted() && g.state.Done():
+		info.Status = "over"
+	case g.isStarted():
+		info.Status = string(Running)
+	}
+	return info
 }
 
 type GameConfig struct {
-	TurnTimeout        int64 `json:"turn_timeout"`
-	ConnectBackTimeout int64 `json:"connect_back_timeout"`
-	MaxHealth          int   `json:"max_health"`
-	MaxEnergy          int   `json:"max_energy"`
-	HealthLoss         int   `json:"health_loss"`
-	LaserDamage        int   `json:"laser_damage"`
-	LaserDistance      int   `json:"laser_distance"`
-	LaserEnergy        int   `json:"laser_energy"`
-	BatteryPower       int   `json:"battery_power"`
-	BatteryHealth      int   `json:"battery_health"`
-}
-
-func (g *Game) join(moniker string) (id string, statech <-chan TurnState,
-	err error) {
+	TurnTimeout        int64        `json:"turn_timeout"`
+	ConnectBackTimeout int64        `json:"connect_back_timeout"`
+	TurnTicks          int          `json:"turn_ticks"`
+	MaxHealth          int          `json:"max_health"`
+	MaxEnergy          int          `json:"max_energy"`
+	HealthLoss         int          `json:"health_loss"`
+	LaserDamage        int          `json:"laser_damage"`
+	LaserDistance      int          `json:"laser_distance"`
+	LaserEnergy        int          `json:"laser_energy"`
+	BatteryPower       int          `json:"battery_power"`
+	BatteryHealth      int          `json:"battery_health"`
+	EnergyCells        PickupConfig `json:"energy_cells"`
+	RepairKits         PickupConfig `json:"repair_kits"`
+	DoubleDamage       PickupConfig `json:"double_damage"`
+	Speed              PickupConfig `json:"speed"`
+	BrickHealth        int          `json:"brick_health"`
+	LavaDamage         int          `json:"lava_damage"`
+	Teams              int          `json:"teams"`
+	FriendlyFire       bool         `json:"friendly_fire"`
+	ShieldEnergy       int          `json:"shield_energy"`
+	ShieldTicks        int          `json:"shield_ticks"`
+	ShieldFront        bool         `json:"shield_front"`
+	Charge             bool         `json:"charge"`
+	ChargeEnergy       int          `json:"charge_energy"`
+	ChargeDamage       int          `json:"charge_damage"`
+	ChargeDistance     int          `json:"charge_distance"`
+	Spread             bool         `json:"spread"`
+	SpreadEnergy       int          `json:"spread_energy"`
+	Mines              bool         `json:"mines"`
+	MineEnergy         int          `json:"mine_energy"`
+	MineDamage         int          `json:"mine_damage"`
+	MaxMines           int          `json:"max_mines"`
+	ShotHeat           int          `json:"shot_heat"`
+	MaxHeat            int          `json:"max_heat"`
+	HeatLoss           int          `json:"heat_loss"`
+	OverheatTicks      int          `json:"overheat_ticks"`
+	Reverse            bool         `json:"reverse"`
+	ReverseEnergy      int          `json:"reverse_energy"`
+	Strafe             bool         `json:"strafe"`
+	StrafeEnergy       int          `json:"strafe_energy"`
+	Map                string       `json:"map,omitempty"`
+	Bounded            bool         `json:"bounded"`
+}
+
+func (g *Game) join(moniker string, opts JoinOptions) (id string,
+	statech <-chan TurnState, config *GameConfig, err error) {
 	g.mtx.Lock()
 	defer g.mtx.Unlock()
-	if len(g.players) >= g.config.NumPlayers {
-		return "", nil, JoinError.New("only %d players allowed",
+	if len(g.state.Players) >= g.config.NumPlayers {
+		return "", nil, nil, JoinError.New("only %d players allowed",
 			g.config.NumPlayers)
 	}
-
-	coord, ok := g.randomEmptyCell()
-	if !ok {
-		panic("grid does not have enough empty cells to place a player!")
+	team := opts.Team
+	if team < 0 || team > g.config.Teams {
+		return "", nil, nil, JoinError.New("no team %d, only %d teams", team,
+			g.config.Teams)
+	}
+	if team == 0 && g.config.Teams > 0 {
+		team = g.smallestTeam()
+	}
+	handicap := g.handicaps[moniker]
+	if opts.Handicap != nil {
+		handicap = *opts.Handicap
 	}
 
+	player, err := g.state.AddHandicappedPlayer(g.config, moniker, handicap,
+		g.rand)
+	if err != nil {
+		return "", nil, nil, err
+	}
 	id = newId()
-	player := &Player{
-		Id:          id,
-		Moniker:     moniker,
-		Owner:       grid.Owner(len(g.players) + 1),
-		Health:      g.config.PlayerHealth,
-		Energy:      g.config.PlayerEnergy,
-		Coord:       coord,
-		Orientation: grid.North,
+	player.Id = id
+	player.Team = team
+	if g.renderer != nil && team != 0 {
+		teams := map[grid.Owner]int{}
+		for _, player := range g.state.Players {
+			teams[player.Owner] = player.Team
+		}
+		g.renderer.SetTeams(teams)
 	}
 
-	g.players = append(g.players, player)
+	config = g.config.PlayerConfig(player)
+	config.Map = g.mapName
+	config.Bounded = g.state.Grid.Bounded()
 
 	statech = g.submitAction(player, Join)
 
-	return id, statech, nil
+	return id, statech, config, nil
+}
+
+// smallestTeam returns the team with the fewest players so far, the first
+// one of them if there is a tie.
+func (g *Game) smallestTeam() int {
+	sizes := make([]int, g.config.Teams+1)
+	for _, player := range g.state.Players {
+		sizes[player.Team]++
+	}
+	smallest := 1
+	for team := 2; team <= g.config.Teams; team++ {
+		if sizes[team] < sizes[smallest] {
+			smallest = team
+		}
+	}
+	return smallest
 }
 
 func (g *Game) TakeTurn(id string, command Command) (state TurnState,
 	err error) {
 	statech, err := g.takeTurn(id, command)
 	if err != nil {
-		return TurnState{}, nil
+		return TurnState{}, err
 	}
 	return <-statech, nil
 }
@@ -313,8 +676,8 @@ func (g *Game) submitAction(player *Player, command Command) (
 	// chan must be buffered so we don't hang up the run loop.
 	statech = make(chan TurnState, 1)
 
-	if g.isStarted() && g.done() {
-		statech <- g.turnState(player)
+	if g.isStarted() && g.state.Done() {
+		statech <- g.state.TurnState(player.Owner)
 	} else {
 		// append the playerAction and signal the run loop
 		g.actionsch <- playerAction{
@@ -328,95 +691,18 @@ func (g *Game) submitAction(player *Player, command Command) (
 }
 
 func (g *Game) isStarted() bool {
-	return len(g.players) >= g.config.NumPlayers
-}
-
-func (g *Game) turnState(player *Player) TurnState {
-	return TurnState{
-		Status:      g.playerStatus(player),
-		Health:      player.Health,
-		Energy:      player.Energy,
-		Orientation: player.Orientation,
-		Grid:        g.grid.SerializeFor(player.Owner),
-	}
-}
-
-func (g *Game) done() bool {
-	return g.aliveCount() < 2
-}
-
-func (g *Game) aliveCount() (count int) {
-	for _, player := range g.players {
-		if player.Alive() {
-			count++
-		}
-	}
-	return count
-}
-
-func (g *Game) getWinner() (winner *Player, ok bool) {
-	count := 0
-	for _, player := range g.players {
-		if player.Alive() {
-			winner = player
-			count++
-		}
-	}
-	if count > 1 {
-		winner = nil
-	}
-	return winner, count <= 1
-
-}
-
-func (g *Game) playerStatus(target *Player) GameStatus {
-	alive := g.aliveCount()
-	switch {
-	case alive == 0:
-		return Draw
-	case alive > 1:
-		return Running
-	case target.Alive():
-		return Won
-	default:
-		return Lost
-	}
+	return len(g.state.Players) >= g.config.NumPlayers
 }
 
-func (g *Game) randomEmptyCell() (grid.Coord, bool) {
-	nogood := map[grid.Coord]bool{}
-	for _, player := range g.players {
-		if player.Alive() {
-			nogood[player.Coord] = true
-		}
-	}
-	for _, battery := range g.batteries {
-		nogood[battery.Coord] = true
-	}
-	for _, laser := range g.lasers {
-		nogood[laser.Coord] = true
-	}
-
-	candidates := make([]grid.Coord, 0, g.grid.Width()*g.grid.Height())
-	for y := 0; y < g.grid.Height(); y++ {
-		for x := 0; x < g.grid.Width(); x++ {
-			coord := grid.Coord{X: x, Y: y}
-			cell := g.grid.CellAt(coord)
-			if cell.Type != grid.Empty || nogood[coord] {
-				continue
-			}
-			candidates = append(candidates, coord)
-		}
-	}
-
-	if len(candidates) == 0 {
-		return grid.Coord{}, false
-	}
-	return candidates[g.rand.Intn(len(candidates))], true
+// Snapshot returns a copy of the game's current state.
+func (g *Game) Snapshot() *Snapshot {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	return g.state.Clone()
 }
 
 func (g *Game) findPlayerById(id string) *Player {
-	for _, player := range g.players {
+	for _, player := range g.state.Players {
 		if player.Id == id {
 			return player
 		}
@@ -424,15 +710,6 @@ func (g *Game) findPlayerById(id string) *Player {
 	return nil
 }
 
-func (g *Game) findPlayerByOwner(owner grid.Owner) *Player {
-	for _, player := range g.players {
-		if player.Owner == owner {
-			return player
-		}
-	}
-	return nil
-}
-
 func (g *Game) run(done_callback func()) {
 
 	// Wait for players to join
@@ -458,7 +735,7 @@ waiting_for_players:
 
 	g.mtx.Lock()
 	g.sendState(actions)
-	g.turn++
+	g.state.Turn++
 	g.mtx.Unlock()
 
 	ticker := time.NewTicker(time.Second / 20)
@@ -467,14 +744,15 @@ waiting_for_players:
 	for done := false; !done; {
 		// collect actions
 		start_time := time.Now()
-		ignore_actions := false
+		// players that ran out of time, whose actions get ignored
+		late := map[grid.Owner]bool{}
 		actions = actions[:0]
 	wait_for_actions:
-		for len(actions) < g.aliveCount() {
+		for len(actions) < g.state.AliveCount() {
 			select {
 			// get an action from a player
 			case action := <-g.actionsch:
-				if ignore_actions {
+				if late[action.player.Owner] {
 					action.command = Noop
 				}
 				if existing := findPlayerAction(actions, action.player); existing != nil {
@@ -488,21 +766,31 @@ waiting_for_players:
 				}
 			case <-ticker.C:
 				elapsed := time.Now().Sub(start_time)
-				if g.config.TurnTimeout > 0 &&
-					elapsed > g.config.TurnTimeout &&
-					!ignore_actions {
-					logger.Noticef("%s passed;  ignoring remaining actions from players", g.config.TurnTimeout)
-					ignore_actions = true
-					g.renderMessage(renderer.Generic, "someone's not responding...")
+				waiting := false
+				for _, player := range g.state.Players {
+					if !player.Alive() || late[player.Owner] ||
+						hasPlayerAction(actions, player) {
+						continue
+					}
+					timeout := player.TurnTimeout
+					if timeout <= 0 || elapsed <= timeout {
+						waiting = true
+						continue
+					}
+					logger.Noticef("%s passed;  ignoring remaining actions from %s", timeout, player)
+					late[player.Owner] = true
+					if len(late) == 1 {
+						g.renderMessage(renderer.Generic, "someone's not responding...")
+					}
 				}
 				switch {
 				case g.config.ConnectBackTimeout <= 0:
-					if ignore_actions {
+					if len(late) > 0 && !waiting {
 						break wait_for_actions
 					}
 				case elapsed > g.config.ConnectBackTimeout:
 					// self-destruct any players that haven't submitted an action
-					for _, player := range g.players {
+					for _, player := range g.state.Players {
 						if !hasPlayerAction(actions, player) {
 							logger.Noticef("%s failed to take turn in %s; self-destruct", player, g.config.ConnectBackTimeout)
 							actions = append(actions, &playerAction{
@@ -517,26 +805,28 @@ waiting_for_players:
 
 		// Apply actions
 		g.mtx.Lock()
-		turn_ticks := 1
-		if g.config.TurnTicks > 0 {
-			turn_ticks = g.config.TurnTicks
+		commands := map[grid.Owner]Command{}
+		for _, action := range actions {
+			logger.Noticef("executing %s for %s", action.command, action.player)
+			commands[action.player.Owner] = action.command
 		}
-		for i := 0; !g.done() && i < turn_ticks; i++ {
-			g.tick(i == 0, actions)
+		logger.Noticef("[turn %d]", g.state.Turn)
+		g.state.turn(g.config, commands, g.rand, func() {
 			g.renderGrid()
-			if winner, ok := g.getWinner(); ok {
+			if winner, ok := g.state.Winner(); ok {
 				if winner == nil {
 					g.renderMessage(renderer.GameOver,
 						"It's a draw :(")
+				} else if winner.Team != 0 {
+					g.renderMessage(renderer.GameOver, "Team %d wins!",
+						winner.Team)
 				} else {
-					g.renderMessage(renderer.GameOver,
-						fmt.Sprintf("%s wins!", winner))
+					g.renderMessage(renderer.GameOver, "%s wins!", winner)
 				}
 			}
-		}
+		})
 		g.sendState(actions)
-		g.turn++
-		done = g.done()
+		done = g.state.Done()
 		g.mtx.Unlock()
 	}
 
@@ -546,235 +836,9 @@ waiting_for_players:
 func (g *Game) sendState(actions []*playerAction) {
 	for _, action := range actions {
 		if action.statech != nil {
-			action.statech <- g.turnState(action.player)
-		}
-	}
-}
-
-func (g *Game) tick(first bool, actions []*playerAction) {
-	g.clearObjects()
-
-	logger.Noticef("[turn %d] first=%t", g.turn, first)
-
-	// clear explosions
-	g.explosions = g.explosions[:0]
-
-	// Do player actions
-	if first {
-		for _, player := range g.players {
-			if !player.Hit(g.config.HealthLoss) {
-				g.newExplosion(player.Coord)
-			}
-		}
-		player_moves := map[grid.Coord][]*Player{}
-		for _, pa := range actions {
-			player, command := pa.player, pa.command
-			logger.Noticef("executing %s for %s", pa.command, pa.player)
-			if !player.Alive() {
-				continue
-			}
-
-			switch command {
-			case Noop:
-			case selfDestruct:
-				player.Health = 0
-				g.newExplosion(player.Coord)
-			case MoveForward:
-				target_cell, target_coord := g.grid.CellRelativeTo(player.Coord,
-					player.Orientation)
-				if target_cell.Type != grid.Wall {
-					player_moves[target_coord] = append(player_moves[target_coord], player)
-				}
-			case RotateLeft:
-				player.Orientation.RotateLeft()
-			case RotateRight:
-				player.Orientation.RotateRight()
-			case FireLaser:
-				// add the laser starting at the players coordinates... it
-				// will move when lasers are handled with below.  The lifetime
-				// is the default lifetime + 1 since it will be decremented
-				// below
-				if player.Energy >= g.config.LaserEnergy {
-					player.Energy -= g.config.LaserEnergy
-					g.lasers = append(g.lasers, &Laser{
-						Coord:       player.Coord,
-						Lifetime:    g.config.LaserLifetime + 1,
-						Owner:       player.Owner,
-						Orientation: player.Orientation,
-					})
-				}
-			}
-		}
-
-		// reconcile the player moves
-	player_check:
-		for coord, players := range player_moves {
-			// make sure another player doesn't already occupy the spot
-			for _, player := range g.players {
-				if player.Alive() && player.Coord == coord {
-					continue player_check
-				}
-			}
-
-			// if multiple players tried to do the same thing, let's let a random one
-			// win
-			player := players[g.rand.Intn(len(players))]
-
-			// did they run into a laser that is heading towards them?
-			for i, laser := range g.lasers {
-				if !(laser.Coord == coord &&
-					laser.Orientation == player.Orientation.Opposite()) {
-					continue
-				}
-
-				g.lasers = append(g.lasers[:i], g.lasers[i+1:]...)
-				player.Hit(g.config.LaserDamage)
-				g.newExplosion(coord)
-				break
-			}
-
-			// did they pick up a battery?
-			for i, battery := range g.batteries {
-				if battery.Coord != coord {
-					continue
-				}
-				g.batteries = append(g.batteries[:i], g.batteries[i+1:]...)
-				player.Energy += g.config.BatteryPower
-				player.Health += g.config.BatteryHealth
-				if player.Energy > g.config.MaxPlayerEnergy {
-					player.Energy = g.config.MaxPlayerEnergy
-				}
-				break
-			}
-			player.Coord = coord
-		}
-	}
-
-	// Fire/expire lasers
-	laser_moves := map[grid.Coord][]*Laser{}
-	for _, laser := range g.lasers {
-		laser.Lifetime--
-		if laser.Lifetime < 1 {
-			continue
-		}
-		target_cell, target_coord := g.grid.CellRelativeTo(laser.Coord,
-			laser.Orientation)
-		if target_cell.Type != grid.Wall {
-			laser_moves[target_coord] = append(laser_moves[target_coord],
-				laser)
-		} else {
-			// laser hit a wall
-			g.newExplosion(target_coord)
-		}
-	}
-	existing_lasers := append([]*Laser(nil), g.lasers...)
-	g.lasers = g.lasers[:0]
-laser_check:
-	for coord, lasers := range laser_moves {
-		if len(lasers) != 1 {
-			// lasers collided, neither one lives... put an explosion
-			g.newExplosion(coord)
-			continue
-		}
-		laser := lasers[0]
-
-		// see if the laser overlaps another laser
-		for _, other_laser := range existing_lasers {
-			if other_laser.Coord == coord &&
-				other_laser.Orientation.Opposite() == laser.Orientation {
-				// cull the other laser out of the laser_moves list, since it
-				// is now dead.
-				for j, laser2 := range laser_moves[laser.Coord] {
-					if laser2 == other_laser {
-						laser_moves[laser.Coord] = append(
-							laser_moves[laser.Coord][:j],
-							laser_moves[laser.Coord][j+1:]...)
-						break
-					}
-				}
-				continue laser_check
-			}
-		}
-
-		// see if the laser hits a player
-		for _, player := range g.players {
-			if !(player.Alive() && player.Coord == coord) {
-				continue
-			}
-			player.Hit(g.config.LaserDamage)
-			g.newExplosion(coord)
-			continue laser_check
-		}
-
-		// see if the laser hits a battery
-		for i, battery := range g.batteries {
-			if battery.Coord == coord {
-				g.batteries = append(g.batteries[:i], g.batteries[i+1:]...)
-				g.newExplosion(coord)
-				continue laser_check
-			}
-		}
-
-		// laser didn't hit anything... move it
-		laser.Coord = coord
-		g.lasers = append(g.lasers, laser)
-	}
-
-	// Spawn battery packs
-	if first && g.config.BatteryTicks > 0 && g.turn%g.config.BatteryTicks == 0 {
-		// Make sure we're not exceeding the max number of batteries
-		if g.config.MaxBatteries < 0 ||
-			len(g.batteries) < g.config.MaxBatteries {
-			if coord, ok := g.randomEmptyCell(); ok {
-				g.batteries = append(g.batteries, Battery{
-					Coord: coord,
-				})
-			}
-		}
-	}
-}
-
-func (g *Game) clearObjects() {
-	// Clear all tracked objects; they will be placed again after all actions
-	// are resolved
-	for _, player := range g.players {
-		if player.Alive() {
-			g.grid.SetCell(player.Coord, grid.EmptyCell)
+			action.statech <- g.state.TurnState(action.player.Owner)
 		}
 	}
-	for _, laser := range g.lasers {
-		g.grid.SetCell(laser.Coord, grid.EmptyCell)
-	}
-	for _, battery := range g.batteries {
-		g.grid.SetCell(battery.Coord, grid.EmptyCell)
-	}
-	for _, explosion_coord := range g.explosions {
-		g.grid.SetCellExploding(explosion_coord, false)
-	}
-}
-
-func (g *Game) setObjects() {
-	// Place all tracked objects in order of rendering priority
-	for _, battery := range g.batteries {
-		g.grid.SetCell(battery.Coord, battery.ToCell())
-	}
-	for _, laser := range g.lasers {
-		g.grid.SetCell(laser.Coord, laser.ToCell())
-	}
-	for _, player := range g.players {
-		if player.Alive() {
-			g.grid.SetCell(player.Coord, player.ToCell())
-		}
-	}
-	// add explosions
-	for _, explosion_coord := range g.explosions {
-		g.grid.SetCellExploding(explosion_coord, true)
-	}
-
-}
-
-func (g *Game) newExplosion(coord grid.Coord) {
-	g.explosions = append(g.explosions, coord)
 }
 
 func (g *Game) renderMessage(msg_type renderer.MessageType, format string,
@@ -786,29 +850,72 @@ func (g *Game) renderMessage(msg_type renderer.MessageType, format string,
 }
 
 func (g *Game) renderGrid() {
-	g.setObjects()
+	g.state.setObjects()
 	if g.renderer == nil {
 		return
 	}
 
 	var statuses []renderer.PlayerStatus
-	for _, player := range g.players {
-		statuses = append(statuses, renderer.PlayerStatus{
-			Moniker: player.Moniker,
-			Health:  ratio(player.Health, g.config.MaxPlayerHealth),
-			Energy:  ratio(player.Energy, g.config.MaxPlayerEnergy),
-		})
+	if g.config.Teams > 0 {
+		statuses = g.teamStatuses()
+	} else {
+		for _, player := range g.state.Players {
+			statuses = append(statuses, renderer.PlayerStatus{
+				Moniker: player.Moniker,
+				Health:  ratio(player.Health, player.maxHealth(g.config)),
+				Energy:  ratio(player.Energy, player.maxEnergy(g.config)),
+				Heat:    heatRatio(player, g.config),
+			})
+		}
 	}
-	if len(statuses) >= 2 {
-		logger.Errore(g.renderer.SetStatus(statuses[:2]))
+	logger.Errore(g.renderer.SetStatus(statuses))
+	logger.Errore(g.renderer.Update(g.state.Grid.Cells()))
+}
+
+// teamStatuses adds up the status of everybody on each team, so a team's
+// bars are full only if every tank on it is.
+func (g *Game) teamStatuses() (statuses []renderer.PlayerStatus) {
+	for team := 1; team <= g.config.Teams; team++ {
+		status := renderer.PlayerStatus{Moniker: fmt.Sprintf("Team %d", team)}
+		members := 0
+		for _, player := range g.state.Players {
+			if player.Team != team {
+				continue
+			}
+			members++
+			status.Health += ratio(player.Health, player.maxHealth(g.config))
+			status.Energy += ratio(player.Energy, player.maxEnergy(g.config))
+			status.Heat += heatRatio(player, g.config)
+		}
+		if members > 0 {
+			status.Health /= float64(members)
+			status.Energy /= float64(members)
+			status.Heat /= float64(members)
+		}
+		statuses = append(statuses, status)
 	}
-	logger.Errore(g.renderer.Update(g.grid.Cells()))
+	return statuses
 }
 
 func ratio(n, d int) float64 {
 	return float64(n) / float64(d)
 }
 
+// heatRatio is how close player is to overheating, or 1 while it is cooling
+// down after having overheated.
+func heatRatio(player *Player, config *Config) float64 {
+	if !player.CanFire() {
+		return 1
+	}
+	if config.MaxHeat <= 0 || player.Heat <= 0 {
+		return 0
+	}
+	if player.Heat >= config.MaxHeat {
+		return 1
+	}
+	return ratio(player.Heat, config.MaxHeat)
+}
+
 func hasPlayerAction(actions []*playerAction, player *Player) bool {
 	return findPlayerAction(actions, player) != nil
 }
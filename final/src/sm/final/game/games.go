@@ -4,6 +4,7 @@ package game
 
 import (
 	"flag"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -23,6 +24,7 @@ type Games struct {
 	mtx    sync.Mutex
 	games  map[string]*Game
 	config *Config
+	pool   *MapPool
 }
 
 func NewGames(config *Config) (
@@ -32,27 +34,65 @@ func NewGames(config *Config) (
 		config: config}
 }
 
+// SetMapPool has new games pick their maps from pool.
+func (g *Games) SetMapPool(pool *MapPool) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	g.pool = pool
+}
+
 func (g *Games) Lookup(name string) *Game {
 	g.mtx.Lock()
 	defer g.mtx.Unlock()
 	return g.games[name]
 }
 
-func (g *Games) LookupOrCreate(name string) (*Game, error) {
+// LookupOrCreate returns the game called name, creating it if it doesn't
+// exist yet, in which case created is true. A new game is played on the map
+// from the pool called map_name, or on whatever map the pool picks if
+// map_name is "". An existing game has to already be on map_name, if it is
+// given.
+func (g *Games) LookupOrCreate(name, map_name string) (game *Game,
+	created bool, err error) {
 	g.mtx.Lock()
 	defer g.mtx.Unlock()
 
-	game := g.games[name]
+	game = g.games[name]
 	if game != nil {
-		return game, nil
+		if map_name != "" && map_name != game.MapName() {
+			return nil, false, MapError.New("game %s is already on map %q",
+				name, game.MapName())
+		}
+		return game, false, nil
+	}
+
+	config := g.config
+	if g.pool != nil {
+		var path string
+		if map_name == "" {
+			map_name, path = g.pool.Pick()
+		} else {
+			path, err = g.pool.Path(map_name)
+			if err != nil {
+				return nil, false, err
+			}
+		}
+		logger.Noticef("game %s is on map %s", name, map_name)
+		pool_config := *g.config
+		pool_config.GridFile = path
+		config = &pool_config
+	} else if map_name != "" {
+		return nil, false, MapError.New(
+			"this server has no maps to choose from")
 	}
 
 	var screen renderer.Renderer
+	var sdl_screen *sdl.SDLRenderer
 	if strings.HasSuffix(name, ":screen") {
-		sdl_screen, err := sdl.NewRenderer(name[:len(name)-len(":screen")],
+		sdl_screen, err = sdl.NewRenderer(name[:len(name)-len(":screen")],
 			*screenWidth, *screenHeight, *numPlayers, *renderTime)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 		go func() {
 			sdl_screen.WaitForQuit()
@@ -61,11 +101,38 @@ func (g *Games) LookupOrCreate(name string) (*Game, error) {
 		screen = sdl_screen
 	}
 
-	game = NewGame(g.config, screen, func() {
+	game, err = NewGame(config, screen, func() {
 		g.mtx.Lock()
 		delete(g.games, name)
 		g.mtx.Unlock()
 	})
+	if err != nil {
+		if sdl_screen != nil {
+			sdl_screen.Close()
+		}
+		return nil, false, err
+	}
 	g.games[name] = game
-	return game, nil
+	return game, true, nil
+}
+
+// List describes every game going on, in order of name.
+func (g *Games) List() []GameInfo {
+	g.mtx.Lock()
+	names := make([]string, 0, len(g.games))
+	for name := range g.games {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	games := make([]*Game, 0, len(names))
+	for _, name := range names {
+		games = append(games, g.games[name])
+	}
+	g.mtx.Unlock()
+
+	infos := make([]GameInfo, 0, len(games))
+	for i, game := range games {
+		infos = append(infos, game.Info(names[i]))
+	}
+	return infos
 }
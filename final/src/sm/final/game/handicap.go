@@ -0,0 +1,127 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package game
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"sm/final/grid"
+)
+
+// Handicap sets one player's starting conditions apart from the rest of the
+// game, for evening out a game between players of different strength.
+// Fields left nil go by the game's config.
+type Handicap struct {
+	Health    *int
+	MaxHealth *int
+	Energy    *int
+	MaxEnergy *int
+	// TurnTimeout is how long the player has each turn, or 0 for as long as
+	// they like.
+	TurnTimeout *time.Duration
+	// Spawn is where the player starts out, facing north, instead of the
+	// map's next spawn point.
+	Spawn *grid.Coord
+}
+
+// ParseHandicap parses a comma separated key=value list like
+// "health=450,max-health=450,timeout=1s,spawn=3/4". The keys are health,
+// max-health, energy, max-energy, timeout and spawn, which is a cell's x
+// and y. A tank can't start out dead or be unable to hold energy, so health,
+// max-health and max-energy have to be more than 0.
+func ParseHandicap(spec string) (handicap Handicap, err error) {
+	for _, item := range strings.Split(spec, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 {
+			return Handicap{}, HandicapError.New("bad handicap %q", item)
+		}
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		ok := true
+		switch key {
+		case "health":
+			handicap.Health, ok = parseAmount(value)
+		case "max-health":
+			handicap.MaxHealth, ok = parseAmount(value)
+		case "energy":
+			handicap.Energy, ok = parseAmount(value)
+		case "max-energy":
+			handicap.MaxEnergy, ok = parseAmount(value)
+		case "timeout":
+			handicap.TurnTimeout, ok = parseTimeout(value)
+		case "spawn":
+			handicap.Spawn, ok = parseSpawn(value)
+		default:
+			return Handicap{}, HandicapError.New("unknown handicap %q", key)
+		}
+		if !ok {
+			return Handicap{}, HandicapError.New("bad handicap %q", item)
+		}
+		switch {
+		case key == "health" && *handicap.Health == 0,
+			key == "max-health" && *handicap.MaxHealth == 0,
+			key == "max-energy" && *handicap.MaxEnergy == 0:
+			return Handicap{}, HandicapError.New("%s has to be more than 0",
+				key)
+		}
+	}
+	return handicap, nil
+}
+
+func parseAmount(value string) (*int, bool) {
+	amount, err := strconv.Atoi(value)
+	if err != nil || amount < 0 {
+		return nil, false
+	}
+	return &amount, true
+}
+
+func parseTimeout(value string) (*time.Duration, bool) {
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return nil, false
+	}
+	return &timeout, true
+}
+
+func parseSpawn(value string) (*grid.Coord, bool) {
+	parts := strings.Split(value, "/")
+	if len(parts) != 2 {
+		return nil, false
+	}
+	x, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, false
+	}
+	y, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, false
+	}
+	return &grid.Coord{X: x, Y: y}, true
+}
+
+// ParseHandicaps parses a semicolon separated list of handicaps by moniker,
+// like "veteran:health=450,max-health=450;rookie:timeout=2s".
+func ParseHandicaps(spec string) (map[string]Handicap, error) {
+	handicaps := map[string]Handicap{}
+	for _, item := range strings.Split(spec, ";") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		parts := strings.SplitN(item, ":", 2)
+		moniker := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || moniker == "" {
+			return nil, HandicapError.New("bad handicap %q", item)
+		}
+		handicap, err := ParseHandicap(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		handicaps[moniker] = handicap
+	}
+	return handicaps, nil
+}
@@ -0,0 +1,221 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package game
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"sm/final/grid"
+)
+
+// joinHandicapped has a player with the handicap in spec join a small map
+// with a wall and one spawn point.
+func joinHandicapped(t *testing.T, config *Config, spec string) (*Player,
+	error) {
+	handicap, err := ParseHandicap(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := grid.ReadMap(strings.NewReader(">W__\n____\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewMapSnapshot(m).AddHandicappedPlayer(config, "player",
+		handicap, nil)
+}
+
+func TestHandicap(t *testing.T) {
+	config := testConfig()
+	config.TurnTimeout = time.Second / 2
+	for _, test := range []struct {
+		spec               string
+		health, max_health int
+		energy, max_energy int
+		timeout            time.Duration
+		x, y               int
+		orientation        grid.Orientation
+	}{
+		{spec: "",
+			health: 300, max_health: 300, energy: 5, max_energy: 10,
+			timeout: time.Second / 2, x: 0, y: 0, orientation: grid.East},
+		{spec: "health=200",
+			health: 200, max_health: 300, energy: 5, max_energy: 10,
+			timeout: time.Second / 2, x: 0, y: 0, orientation: grid.East},
+		{spec: "max-health=450",
+			health: 300, max_health: 450, energy: 5, max_energy: 10,
+			timeout: time.Second / 2, x: 0, y: 0, orientation: grid.East},
+		// starting health is cut down to fit, unless it was asked for
+		{spec: "max-health=100",
+			health: 100, max_health: 100, energy: 5, max_energy: 10,
+			timeout: time.Second / 2, x: 0, y: 0, orientation: grid.East},
+		{spec: "energy=8",
+			health: 300, max_health: 300, energy: 8, max_energy: 10,
+			timeout: time.Second / 2, x: 0, y: 0, orientation: grid.East},
+		{spec: "energy=0",
+			health: 300, max_health: 300, energy: 0, max_energy: 10,
+			timeout: time.Second / 2, x: 0, y: 0, orientation: grid.East},
+		{spec: "max-energy=3",
+			health: 300, max_health: 300, energy: 3, max_energy: 3,
+			timeout: time.Second / 2, x: 0, y: 0, orientation: grid.East},
+		{spec: "timeout=2s",
+			health: 300, max_health: 300, energy: 5, max_energy: 10,
+			timeout: 2 * time.Second, x: 0, y: 0, orientation: grid.East},
+		{spec: "timeout=0s",
+			health: 300, max_health: 300, energy: 5, max_energy: 10,
+			timeout: 0, x: 0, y: 0, orientation: grid.East},
+		{spec: "spawn=2/1",
+			health: 300, max_health: 300, energy: 5, max_energy: 10,
+			timeout: time.Second / 2, x: 2, y: 1, orientation: grid.North},
+	} {
+		player, err := joinHandicapped(t, config, test.spec)
+		if err != nil {
+			t.Errorf("%q: %v", test.spec, err)
+			continue
+		}
+		if player.Health != test.health ||
+			player.maxHealth(config) != test.max_health ||
+			player.Energy != test.energy ||
+			player.maxEnergy(config) != test.max_energy ||
+			player.TurnTimeout != test.timeout ||
+			player.Coord != (grid.Coord{X: test.x, Y: test.y}) ||
+			player.Orientation != test.orientation {
+			t.Errorf("%q: unexpected player %+v", test.spec, *player)
+		}
+		player_config := config.PlayerConfig(player)
+		if player_config.TurnTimeout != int64(test.timeout) ||
+			player_config.MaxHealth != test.max_health ||
+			player_config.MaxEnergy != test.max_energy {
+			t.Errorf("%q: unexpected config %+v", test.spec, player_config)
+		}
+	}
+}
+
+func TestHandicapErrors(t *testing.T) {
+	for _, test := range []struct {
+		spec, want string
+	}{
+		{"health=0", "health has to be more than 0"},
+		{"max-health=0", "max-health has to be more than 0"},
+		{"max-energy=0", "max-energy has to be more than 0"},
+		{"health=-1", "bad handicap"},
+		{"energy=lots", "bad handicap"},
+		{"timeout=-1s", "bad handicap"},
+		{"timeout=2", "bad handicap"},
+		{"spawn=1", "bad handicap"},
+		{"health", "bad handicap"},
+		{"speed=2", "unknown handicap"},
+	} {
+		_, err := ParseHandicap(test.spec)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%q: expected an error about %q, got %v", test.spec,
+				test.want, err)
+		}
+	}
+
+	config := testConfig()
+	for _, spec := range []string{
+		"health=400",
+		"energy=20,max-energy=15",
+		"spawn=4/0",
+		"spawn=1/0",
+	} {
+		if _, err := joinHandicapped(t, config, spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestParseHandicaps(t *testing.T) {
+	handicaps, err := ParseHandicaps(
+		"veteran:health=450,max-health=450; rookie:timeout=0s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	veteran, rookie := handicaps["veteran"], handicaps["rookie"]
+	if len(handicaps) != 2 || veteran.Health == nil ||
+		*veteran.Health != 450 || veteran.Energy != nil ||
+		rookie.TurnTimeout == nil || *rookie.TurnTimeout != 0 {
+		t.Fatalf("unexpected handicaps %+v", handicaps)
+	}
+
+	for _, spec := range []string{"veteran", ":health=450",
+		"veteran:health=0"} {
+		if _, err := ParseHandicaps(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestBadHandicapsRefuseGames(t *testing.T) {
+	config := testConfig()
+	config.GridFile, config.MapDir = "", ""
+	config.Handicaps = "veteran:speed=2"
+	if _, err := NewGame(config, nil, func() {}); err == nil {
+		t.Fatalf("expected a game with bad handicaps to fail")
+	}
+
+	games := NewGames(config)
+	if _, _, err := games.LookupOrCreate("bad", ""); err == nil {
+		t.Fatalf("expected a game with bad handicaps to fail")
+	}
+	if games.Lookup("bad") != nil {
+		t.Fatalf("expected no game with bad handicaps")
+	}
+}
+
+func TestTickHandicaps(t *testing.T) {
+	config := testConfig()
+	health, energy := config.PlayerHealth, config.PlayerEnergy
+	rows := []string{"_____", "__^__", "_____", "____v"}
+	// handicap swaps player 1 for one that joined with spec
+	handicap := func(spec string) func(s *Snapshot) {
+		return func(s *Snapshot) {
+			h, err := ParseHandicap(spec)
+			if err != nil {
+				panic(err)
+			}
+			s.Grid.SetCell(s.Players[0].Coord, grid.Cell{Type: grid.Empty})
+			s.Players = s.Players[1:]
+			player, err := s.AddHandicappedPlayer(config, "player1", h, nil)
+			if err != nil {
+				panic(err)
+			}
+			player.Owner = 1
+			s.Players = append([]*Player{player}, s.Players...)
+			s.Grid.SetCell(player.Coord, player.ToCell())
+		}
+	}
+	runTickTests(t, []tickTest{
+		{
+			name:  "a tank that starts without energy can't fire",
+			rows:  []string{">___<"},
+			setup: handicap("energy=0,spawn=0/0"),
+			turns: []map[grid.Owner]Command{{1: FireLaser}, nil, nil},
+			check: func(s *Snapshot, events []Event) string {
+				if problem := expectHealth(s, 2, health); problem != "" {
+					return problem
+				}
+				return expectPlayer(s, 1, 0, 0, grid.North, health, 0)
+			},
+		},
+		{
+			name: "batteries only fill a tank up to its own max",
+			rows: rows,
+			setup: func(s *Snapshot) {
+				handicap("max-energy=6,spawn=2/1")(s)
+				s.Batteries = append(s.Batteries,
+					Pickup{Coord: grid.Coord{X: 2, Y: 0}, Kind: grid.Battery})
+			},
+			turns: []map[grid.Owner]Command{{1: MoveForward}},
+			check: func(s *Snapshot, events []Event) string {
+				if energy+config.BatteryPower <= 6 {
+					return "the battery doesn't give enough to hit the max"
+				}
+				return expectPlayer(s, 1, 2, 0, grid.North,
+					health+config.BatteryHealth, 6)
+			},
+		},
+	})
+}
@@ -0,0 +1,125 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package game
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"sm/final/grid"
+)
+
+func TestTickHazards(t *testing.T) {
+	config := testConfig()
+	health, energy := config.PlayerHealth, config.PlayerEnergy
+	move := []map[grid.Owner]Command{{1: MoveForward}}
+	runTickTests(t, []tickTest{
+		{
+			name: "lava burns every tick",
+			rows: []string{"__~__", "__^__", "____v"},
+			config: func(config *Config) {
+				config.TurnTicks = 2
+			},
+			turns: []map[grid.Owner]Command{{1: MoveForward}, nil},
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 2, 0, grid.North,
+					health-4*config.LavaDamage, energy)
+			},
+		},
+		{
+			name:  "and stays lava after driving off",
+			rows:  []string{"_____", "__~__", "__^__", "____v"},
+			turns: []map[grid.Owner]Command{{1: MoveForward}, {1: MoveForward}},
+			check: func(s *Snapshot, events []Event) string {
+				if cell := s.Grid.CellAt(grid.Coord{X: 2, Y: 1}); cell.Type !=
+					grid.Lava {
+					return fmt.Sprintf("expected lava, got %s", cell.Type)
+				}
+				return expectPlayer(s, 1, 2, 0, grid.North,
+					health-config.LavaDamage, energy)
+			},
+		},
+		{
+			name:  "ice slides tanks on",
+			rows:  []string{"_____", "_____", "__*__", "__^__", "____v"},
+			turns: move,
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 2, 1, grid.North, health, energy)
+			},
+		},
+		{
+			name:  "but not into walls",
+			rows:  []string{"_____", "__W__", "__*__", "__^__", "____v"},
+			turns: move,
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 2, 2, grid.North, health, energy)
+			},
+		},
+		{
+			name:  "slow ground gets tanks stuck",
+			rows:  []string{"_____", "_____", "__%__", "__^__", "____v"},
+			turns: move,
+			check: func(s *Snapshot, events []Event) string {
+				if !s.FindPlayer(1).Stuck {
+					return "the tank isn't stuck"
+				}
+				return expectPlayer(s, 1, 2, 2, grid.North, health, energy)
+			},
+		},
+		{
+			name:  "and takes a move to get going again",
+			rows:  []string{"_____", "_____", "__%__", "__^__", "____v"},
+			turns: []map[grid.Owner]Command{{1: MoveForward}, {1: MoveForward}},
+			check: func(s *Snapshot, events []Event) string {
+				if s.FindPlayer(1).Stuck {
+					return "the tank is still stuck"
+				}
+				return expectPlayer(s, 1, 2, 2, grid.North, health, energy)
+			},
+		},
+		{
+			name: "and then it's fine",
+			rows: []string{"_____", "_____", "__%__", "__^__", "____v"},
+			turns: []map[grid.Owner]Command{{1: MoveForward}, {1: MoveForward},
+				{1: MoveForward}},
+			check: func(s *Snapshot, events []Event) string {
+				if s.FindPlayer(1).Stuck {
+					return "the tank is still stuck"
+				}
+				return expectPlayer(s, 1, 2, 1, grid.North, health, energy)
+			},
+		},
+	})
+}
+
+func TestTickHazardsWithoutMap(t *testing.T) {
+	config := testConfig()
+	m, err := grid.ReadMap(strings.NewReader("_____\n__~__\n_____\n_____\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSnapshot(m.Grid)
+	for _, coord := range []grid.Coord{{X: 2, Y: 2}, {X: 4, Y: 3}} {
+		spawn := coord
+		_, err := s.AddHandicappedPlayer(config, "player",
+			Handicap{Spawn: &spawn}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	actions := map[grid.Owner]Command{1: MoveForward}
+	next, _ := s.Next(config, actions, rng)
+	next, _ = next.Next(config, actions, rng)
+	if cell := next.Grid.CellAt(grid.Coord{X: 2, Y: 1}); cell.Type !=
+		grid.Lava {
+		t.Fatalf("expected lava, got %s", cell.Type)
+	}
+	if problem := expectHealth(next, 1,
+		config.PlayerHealth-config.LavaDamage); problem != "" {
+		t.Fatal(problem)
+	}
+}
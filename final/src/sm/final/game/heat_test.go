@@ -0,0 +1,77 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package game
+
+import (
+	"fmt"
+	"testing"
+
+	"sm/final/grid"
+)
+
+func TestTickHeat(t *testing.T) {
+	heat := func(config *Config) {
+		config.ShotHeat = 4
+		config.MaxHeat = 10
+		config.HeatLoss = 1
+		config.OverheatTicks = 3
+	}
+	fire := func(turns int) (actions []map[grid.Owner]Command) {
+		for i := 0; i < turns; i++ {
+			actions = append(actions, map[grid.Owner]Command{1: FireLaser})
+		}
+		return actions
+	}
+	expectHeat := func(fired, overheated, heat, cooldown int) func(
+		s *Snapshot, events []Event) string {
+		return func(s *Snapshot, events []Event) string {
+			player := s.FindPlayer(1)
+			got := fmt.Sprintf("fired %d, overheated %d, heat %d, cooldown %d",
+				countEvents(events, LaserFired),
+				countEvents(events, Overheated), player.Heat, player.Cooldown)
+			want := fmt.Sprintf("fired %d, overheated %d, heat %d, cooldown %d",
+				fired, overheated, heat, cooldown)
+			if got != want {
+				return fmt.Sprintf("%s, not %s", got, want)
+			}
+			return ""
+		}
+	}
+	rows := []string{"^____", "_____", "_____", "____v"}
+	runTickTests(t, []tickTest{
+		{
+			name:  "no heat by default",
+			rows:  rows,
+			turns: fire(4),
+			check: expectHeat(4, 0, 0, 0),
+		},
+		{
+			name:   "firing heats up",
+			rows:   rows,
+			config: heat,
+			turns:  fire(2),
+			check:  expectHeat(2, 0, 6, 0),
+		},
+		{
+			name:   "and cools down",
+			rows:   rows,
+			config: heat,
+			turns:  append(fire(2), nil, nil),
+			check:  expectHeat(2, 0, 4, 0),
+		},
+		{
+			name:   "too much and weapons overheat",
+			rows:   rows,
+			config: heat,
+			turns:  fire(4),
+			check:  expectHeat(3, 1, 0, 1),
+		},
+		{
+			name:   "until they cool off",
+			rows:   rows,
+			config: heat,
+			turns:  fire(6),
+			check:  expectHeat(4, 1, 3, 0),
+		},
+	})
+}
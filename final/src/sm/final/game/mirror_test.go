@@ -0,0 +1,51 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package game
+
+import (
+	"testing"
+
+	"sm/final/grid"
+)
+
+func TestTickMirrors(t *testing.T) {
+	config := testConfig()
+	health := config.PlayerHealth
+	shot := func(owner grid.Owner) func(s *Snapshot, events []Event) string {
+		return func(s *Snapshot, events []Event) string {
+			return expectHealth(s, owner, health-config.LaserDamage)
+		}
+	}
+	fire := []map[grid.Owner]Command{{1: FireLaser}, nil, nil, nil}
+	runTickTests(t, []tickTest{
+		{
+			name:  "lasers bounce off / mirrors",
+			rows:  []string{"__v__", "_____", ">_/__"},
+			turns: fire,
+			check: shot(2),
+		},
+		{
+			name:  "and off \\ mirrors",
+			rows:  []string{">_\\__", "__^__"},
+			turns: fire,
+			check: shot(2),
+		},
+		{
+			name:  "even back at whoever fired",
+			rows:  []string{"/_<__", "\\_/__", "____^"},
+			turns: fire,
+			check: shot(1),
+		},
+		{
+			name:  "tanks can't drive through them",
+			rows:  []string{">/__<"},
+			turns: []map[grid.Owner]Command{{1: MoveForward}},
+			check: func(s *Snapshot, events []Event) string {
+				if s.FindPlayer(1).Coord.X != 0 {
+					return "the tank drove through the mirror"
+				}
+				return ""
+			},
+		},
+	})
+}
@@ -0,0 +1,81 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package game
+
+import (
+	"testing"
+
+	"sm/final/grid"
+)
+
+func TestTickMovement(t *testing.T) {
+	config := testConfig()
+	health, energy := config.PlayerHealth, config.PlayerEnergy
+	rows := []string{"_____", "__^__", "_____", "____v"}
+	runTickTests(t, []tickTest{
+		{
+			name:  "backing up is off by default",
+			rows:  rows,
+			turns: []map[grid.Owner]Command{{1: Reverse}},
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 2, 1, grid.North, health, energy)
+			},
+		},
+		{
+			name: "backing up keeps tanks facing the same way",
+			rows: rows,
+			config: func(config *Config) {
+				config.Reverse = true
+				config.ReverseEnergy = 1
+			},
+			turns: []map[grid.Owner]Command{{1: Reverse}},
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 2, 2, grid.North, health, energy-1)
+			},
+		},
+		{
+			name:  "strafing is off by default",
+			rows:  rows,
+			turns: []map[grid.Owner]Command{{1: StrafeLeft}},
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 2, 1, grid.North, health, energy)
+			},
+		},
+		{
+			name: "strafing goes sideways",
+			rows: rows,
+			config: func(config *Config) {
+				config.Strafe = true
+			},
+			turns: []map[grid.Owner]Command{{1: StrafeLeft},
+				{1: StrafeRight}, {1: StrafeRight}},
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 3, 1, grid.North, health,
+					energy-3*config.StrafeEnergy)
+			},
+		},
+		{
+			name: "into walls for free",
+			rows: []string{"_____", "_W^__", "_____", "____v"},
+			config: func(config *Config) {
+				config.Strafe = true
+			},
+			turns: []map[grid.Owner]Command{{1: StrafeLeft}},
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 2, 1, grid.North, health, energy)
+			},
+		},
+		{
+			name: "and not without the energy for it",
+			rows: rows,
+			config: func(config *Config) {
+				config.Strafe = true
+				config.StrafeEnergy = energy + 1
+			},
+			turns: []map[grid.Owner]Command{{1: StrafeLeft}},
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 2, 1, grid.North, health, energy)
+			},
+		},
+	})
+}
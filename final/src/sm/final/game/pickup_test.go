@@ -0,0 +1,127 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package game
+
+import (
+	"fmt"
+	"testing"
+
+	"sm/final/grid"
+)
+
+func TestTickPickups(t *testing.T) {
+	config := testConfig()
+	health, energy := config.PlayerHealth, config.PlayerEnergy
+	rows := []string{"_____", "__^__", "_____", "____v"}
+	pickup := func(kind grid.Type, x, y int) func(s *Snapshot) {
+		return func(s *Snapshot) {
+			s.Batteries = append(s.Batteries,
+				Pickup{Coord: grid.Coord{X: x, Y: y}, Kind: kind})
+		}
+	}
+	runTickTests(t, []tickTest{
+		{
+			name: "energy cells only give energy",
+			rows: rows,
+			config: func(config *Config) {
+				config.PlayerHealth = 100
+				config.MaxPlayerEnergy = energy + 2
+			},
+			setup: pickup(grid.EnergyCell, 2, 0),
+			turns: []map[grid.Owner]Command{{1: MoveForward}},
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 2, 0, grid.North, 100, energy+2)
+			},
+		},
+		{
+			name: "repair kits only give health",
+			rows: rows,
+			config: func(config *Config) {
+				config.PlayerHealth = 100
+			},
+			setup: pickup(grid.RepairKit, 2, 0),
+			turns: []map[grid.Owner]Command{{1: MoveForward}},
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 2, 0, grid.North,
+					100+config.RepairKits.Amount, energy)
+			},
+		},
+		{
+			name: "repair kits stop at max health",
+			rows: rows,
+			config: func(config *Config) {
+				config.PlayerHealth = config.MaxPlayerHealth - 1
+			},
+			setup: pickup(grid.RepairKit, 2, 0),
+			turns: []map[grid.Owner]Command{{1: MoveForward}},
+			check: func(s *Snapshot, events []Event) string {
+				return expectHealth(s, 1, config.MaxPlayerHealth)
+			},
+		},
+		{
+			name: "batteries don't stop at max health",
+			rows: rows,
+			config: func(config *Config) {
+				config.PlayerHealth = config.MaxPlayerHealth - 1
+			},
+			setup: pickup(grid.Battery, 2, 0),
+			turns: []map[grid.Owner]Command{{1: MoveForward}},
+			check: func(s *Snapshot, events []Event) string {
+				return expectHealth(s, 1,
+					config.MaxPlayerHealth-1+config.BatteryHealth)
+			},
+		},
+		{
+			name:  "double damage doubles laser damage",
+			rows:  []string{">___<"},
+			setup: pickup(grid.DoubleDamage, 1, 0),
+			turns: []map[grid.Owner]Command{{1: MoveForward},
+				{1: FireLaser}, nil, nil},
+			check: func(s *Snapshot, events []Event) string {
+				return expectHealth(s, 2, health-2*config.LaserDamage)
+			},
+		},
+		{
+			name: "speed drives on every tick",
+			rows: rows,
+			config: func(config *Config) {
+				config.TurnTicks = 2
+			},
+			setup: pickup(grid.Speed, 2, 0),
+			turns: []map[grid.Owner]Command{{1: MoveForward}},
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 2, 3, grid.North, health, energy)
+			},
+		},
+		{
+			name:  "lasers destroy pickups",
+			rows:  rows,
+			setup: pickup(grid.RepairKit, 2, 0),
+			turns: []map[grid.Owner]Command{{1: FireLaser}},
+			check: func(s *Snapshot, events []Event) string {
+				if countEvents(events, BatteryDestroyed) != 1 ||
+					len(s.Batteries) != 0 {
+					return "the repair kit is still there"
+				}
+				return ""
+			},
+		},
+		{
+			name: "pickups spawn on their own",
+			rows: rows,
+			config: func(config *Config) {
+				config.EnergyCells.Ticks = 1
+				config.EnergyCells.Max = 2
+			},
+			turns: []map[grid.Owner]Command{nil, nil, nil},
+			check: func(s *Snapshot, events []Event) string {
+				if len(s.Batteries) != 2 ||
+					s.Batteries[0].Kind != grid.EnergyCell {
+					return fmt.Sprintf("expected 2 energy cells, got %v",
+						s.Batteries)
+				}
+				return ""
+			},
+		},
+	})
+}
@@ -0,0 +1,144 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package game
+
+import (
+	math_rand "math/rand"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sm/final/grid"
+)
+
+const (
+	// RandomOrder picks maps at random, more often the higher their weight.
+	RandomOrder = "random"
+	// RotationOrder goes through the maps in order of name, starting over
+	// once it has been through them all, for tournaments.
+	RotationOrder = "rotation"
+)
+
+// MapPool is a directory of maps for games to be played on.
+type MapPool struct {
+	mtx   sync.Mutex
+	maps  []poolMap
+	order string
+	next  int
+	rand  *math_rand.Rand
+}
+
+type poolMap struct {
+	name   string
+	path   string
+	weight int
+}
+
+// LoadMapPool loads every .map file in dir. Maps are named after their file,
+// without the .map, so final/maps/1.map is called 1. order is RandomOrder or
+// RotationOrder, and weights is a comma separated list like "1=3,2=0"
+// setting how often maps come up: maps without a weight have a weight of 1,
+// and maps with a weight of 0 are only played when asked for by name.
+func LoadMapPool(dir, order, weights string) (*MapPool, error) {
+	if order != RandomOrder && order != RotationOrder {
+		return nil, MapError.New("unknown map order %q", order)
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.map"))
+	if err != nil {
+		return nil, MapError.Wrap(err)
+	}
+	sort.Strings(paths)
+
+	p := &MapPool{
+		order: order,
+		rand:  math_rand.New(math_rand.NewSource(time.Now().UnixNano())),
+	}
+	names := map[string]int{}
+	for _, path := range paths {
+		// make sure it will load when it comes up
+		_, err := grid.LoadMap(path)
+		if err != nil {
+			return nil, MapError.New("%s: %v", path, err)
+		}
+		name := strings.TrimSuffix(filepath.Base(path), ".map")
+		names[name] = len(p.maps)
+		p.maps = append(p.maps, poolMap{name: name, path: path, weight: 1})
+	}
+	if len(p.maps) == 0 {
+		return nil, MapError.New("no maps in %s", dir)
+	}
+
+	for _, item := range strings.Split(weights, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		parts := strings.SplitN(item, "=", 2)
+		i, ok := names[strings.TrimSpace(parts[0])]
+		if !ok || len(parts) != 2 {
+			return nil, MapError.New("bad map weight %q", item)
+		}
+		weight, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || weight < 0 {
+			return nil, MapError.New("bad map weight %q", item)
+		}
+		p.maps[i].weight = weight
+	}
+	total := 0
+	for _, m := range p.maps {
+		total += m.weight
+	}
+	if total == 0 {
+		return nil, MapError.New("every map in %s has a weight of 0", dir)
+	}
+	return p, nil
+}
+
+// Names returns the names of the maps in the pool.
+func (p *MapPool) Names() (names []string) {
+	for _, m := range p.maps {
+		names = append(names, m.name)
+	}
+	return names
+}
+
+// Path returns where the map called name is.
+func (p *MapPool) Path(name string) (string, error) {
+	for _, m := range p.maps {
+		if m.name == name {
+			return m.path, nil
+		}
+	}
+	return "", MapError.New("no map called %q", name)
+}
+
+// Pick chooses the map for the next game, returning its name and where it
+// is.
+func (p *MapPool) Pick() (name, path string) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.order == RotationOrder {
+		for {
+			m := p.maps[p.next%len(p.maps)]
+			p.next++
+			if m.weight > 0 {
+				return m.name, m.path
+			}
+		}
+	}
+
+	total := 0
+	for _, m := range p.maps {
+		total += m.weight
+	}
+	n := p.rand.Intn(total)
+	for _, m := range p.maps {
+		if n < m.weight {
+			return m.name, m.path
+		}
+		n -= m.weight
+	}
+	panic("unreachable")
+}
@@ -0,0 +1,99 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package game
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestPool returns a directory with a small map file for each name.
+func newTestPool(t *testing.T, names ...string) string {
+	dir, err := ioutil.TempDir("", "pool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		err := ioutil.WriteFile(filepath.Join(dir, name+".map"),
+			[]byte(">__\n___\n__<\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestMapPoolRotation(t *testing.T) {
+	dir := newTestPool(t, "b", "a", "c")
+	defer os.RemoveAll(dir)
+	pool, err := LoadMapPool(dir, RotationOrder, "b=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := strings.Join(pool.Names(), ","); names != "a,b,c" {
+		t.Fatalf("expected a,b,c, got %s", names)
+	}
+
+	var picked []string
+	for i := 0; i < 4; i++ {
+		name, path := pool.Pick()
+		if path != filepath.Join(dir, name+".map") {
+			t.Fatalf("%s is at %s", name, path)
+		}
+		picked = append(picked, name)
+	}
+	if got := strings.Join(picked, ","); got != "a,c,a,c" {
+		t.Fatalf("expected a,c,a,c, got %s", got)
+	}
+
+	// maps that never come up on their own can still be asked for
+	if _, err := pool.Path("b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Path("d"); err == nil {
+		t.Fatal("expected an error for a map that isn't there")
+	}
+}
+
+func TestMapPoolRandomWeights(t *testing.T) {
+	dir := newTestPool(t, "a", "b")
+	defer os.RemoveAll(dir)
+	pool, err := LoadMapPool(dir, RandomOrder, "a=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		if name, _ := pool.Pick(); name != "b" {
+			t.Fatalf("picked %s, which has a weight of 0", name)
+		}
+	}
+}
+
+func TestMapPoolErrors(t *testing.T) {
+	dir := newTestPool(t, "a", "b")
+	defer os.RemoveAll(dir)
+	for _, test := range []struct {
+		order, weights string
+	}{
+		{"sideways", ""},
+		{RandomOrder, "c=1"},
+		{RandomOrder, "a"},
+		{RandomOrder, "a=-1"},
+		{RandomOrder, "a=x"},
+		{RandomOrder, "a=0,b=0"},
+	} {
+		_, err := LoadMapPool(dir, test.order, test.weights)
+		if err == nil {
+			t.Errorf("%s %q: expected an error", test.order, test.weights)
+		}
+	}
+
+	empty := newTestPool(t)
+	defer os.RemoveAll(empty)
+	if _, err := LoadMapPool(empty, RandomOrder, ""); err == nil {
+		t.Error("expected an error for a directory without maps")
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package game
+
+import (
+	"testing"
+
+	"sm/final/grid"
+)
+
+func TestTickPortals(t *testing.T) {
+	config := testConfig()
+	health, energy := config.PlayerHealth, config.PlayerEnergy
+	runTickTests(t, []tickTest{
+		{
+			name:  "tanks drive through portals",
+			rows:  []string{"__1__", "__^__", "_____", "___1v"},
+			turns: []map[grid.Owner]Command{{1: MoveForward}},
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 3, 2, grid.North, health, energy)
+			},
+		},
+		{
+			name:  "but not into somebody coming out the other side",
+			rows:  []string{"__1__", "__^__", "___v_", "___1_"},
+			turns: []map[grid.Owner]Command{{1: MoveForward}},
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 2, 1, grid.North, health, energy)
+			},
+		},
+		{
+			name:  "lasers go through portals",
+			rows:  []string{"__1__", "__^__", "___v_", "___1_"},
+			turns: []map[grid.Owner]Command{{1: FireLaser}, nil},
+			check: func(s *Snapshot, events []Event) string {
+				return expectHealth(s, 2, health-config.LaserDamage)
+			},
+		},
+		{
+			name:  "a portal leading right back gets a tank nowhere",
+			rows:  []string{"_____", "_1^1_", "____v"},
+			turns: []map[grid.Owner]Command{{1: RotateLeft}, {1: MoveForward}},
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 2, 1, grid.West, health, energy)
+			},
+		},
+	})
+}
@@ -0,0 +1,89 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package game
+
+import (
+	"testing"
+
+	"sm/final/grid"
+)
+
+func TestTickShields(t *testing.T) {
+	config := testConfig()
+	health, energy := config.PlayerHealth, config.PlayerEnergy
+	shields := func(config *Config) {
+		config.ShieldTicks = 4
+	}
+	shielded_shot := []map[grid.Owner]Command{{1: FireLaser, 2: Shield}, nil,
+		nil, nil}
+	runTickTests(t, []tickTest{
+		{
+			name:  "shields are off by default",
+			rows:  []string{">___<"},
+			turns: shielded_shot,
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 2, 4, 0, grid.West,
+					health-config.LaserDamage, energy)
+			},
+		},
+		{
+			name:   "shields block lasers",
+			rows:   []string{">___<"},
+			config: shields,
+			turns:  shielded_shot,
+			check: func(s *Snapshot, events []Event) string {
+				if countEvents(events, ShieldBlocked) != 1 {
+					return "the shield didn't block the laser"
+				}
+				return expectPlayer(s, 2, 4, 0, grid.West, health,
+					energy-config.ShieldEnergy)
+			},
+		},
+		{
+			name:   "until they run out",
+			rows:   []string{">____<"},
+			config: shields,
+			turns:  append(shielded_shot, nil),
+			check: func(s *Snapshot, events []Event) string {
+				return expectHealth(s, 2, health-config.LaserDamage)
+			},
+		},
+		{
+			name: "front shields block lasers from the front",
+			rows: []string{">___<"},
+			config: func(config *Config) {
+				config.ShieldTicks = 4
+				config.ShieldFront = true
+			},
+			turns: shielded_shot,
+			check: func(s *Snapshot, events []Event) string {
+				return expectHealth(s, 2, health)
+			},
+		},
+		{
+			name: "but not from the side",
+			rows: []string{">___^"},
+			config: func(config *Config) {
+				config.ShieldTicks = 4
+				config.ShieldFront = true
+			},
+			turns: shielded_shot,
+			check: func(s *Snapshot, events []Event) string {
+				return expectHealth(s, 2, health-config.LaserDamage)
+			},
+		},
+		{
+			name: "shields take energy",
+			rows: []string{">___<"},
+			config: func(config *Config) {
+				config.ShieldTicks = 4
+				config.ShieldEnergy = energy + 1
+			},
+			turns: shielded_shot,
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 2, 4, 0, grid.West,
+					health-config.LaserDamage, energy)
+			},
+		},
+	})
+}
@@ -0,0 +1,75 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package game
+
+import (
+	"fmt"
+	"testing"
+
+	"sm/final/grid"
+)
+
+func TestTickMapSpawns(t *testing.T) {
+	batteryAt := func(x, y int) func(s *Snapshot, events []Event) string {
+		return func(s *Snapshot, events []Event) string {
+			if len(s.Batteries) != 1 ||
+				s.Batteries[0].Coord != (grid.Coord{X: x, Y: y}) {
+				return fmt.Sprintf("expected a battery at (%d,%d), got %v",
+					x, y, s.Batteries)
+			}
+			return ""
+		}
+	}
+	spawn := func(config *Config) {
+		config.BatteryTicks = 1
+		config.MaxBatteries = 1
+	}
+	runTickTests(t, []tickTest{
+		{
+			name:  "players start on spawns facing their way",
+			rows:  []string{"v____", "_____", "____^"},
+			turns: []map[grid.Owner]Command{nil},
+			check: func(s *Snapshot, events []Event) string {
+				if problem := expectPlayer(s, 1, 0, 0, grid.South,
+					s.Players[0].Health, s.Players[0].Energy); problem != "" {
+					return problem
+				}
+				return expectPlayer(s, 2, 4, 2, grid.North,
+					s.Players[1].Health, s.Players[1].Energy)
+			},
+		},
+		{
+			name:   "batteries show up on spawners",
+			rows:   []string{">___<", "b___B"},
+			config: spawn,
+			turns:  []map[grid.Owner]Command{nil},
+			check:  batteryAt(4, 1),
+		},
+		{
+			name:   "and in zones when the spawners are taken",
+			rows:   []string{">___<", "b___B"},
+			config: spawn,
+			setup: func(s *Snapshot) {
+				s.Grid.SetCell(grid.Coord{X: 4, Y: 1}, grid.WallCell)
+			},
+			turns: []map[grid.Owner]Command{nil},
+			check: batteryAt(0, 1),
+		},
+		{
+			name:   "and nowhere else",
+			rows:   []string{">___<", "b___B"},
+			config: spawn,
+			setup: func(s *Snapshot) {
+				s.Grid.SetCell(grid.Coord{X: 0, Y: 1}, grid.WallCell)
+				s.Grid.SetCell(grid.Coord{X: 4, Y: 1}, grid.WallCell)
+			},
+			turns: []map[grid.Owner]Command{nil},
+			check: func(s *Snapshot, events []Event) string {
+				if len(s.Batteries) != 0 {
+					return fmt.Sprintf("unexpected batteries %v", s.Batteries)
+				}
+				return ""
+			},
+		},
+	})
+}
@@ -0,0 +1,1022 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package game
+
+import (
+	"math/rand"
+
+	"sm/final/grid"
+)
+
+type EventType string
+
+const (
+	LaserFired       EventType = "laser-fired"
+	LaserHitWall     EventType = "laser-hit-wall"
+	BrickDestroyed   EventType = "brick-destroyed"
+	ShieldRaised     EventType = "shield-raised"
+	ShieldBlocked    EventType = "shield-blocked"
+	Overheated       EventType = "overheated"
+	MineDropped      EventType = "mine-dropped"
+	MineDetonated    EventType = "mine-detonated"
+	MineDestroyed    EventType = "mine-destroyed"
+	LasersCollided   EventType = "lasers-collided"
+	PlayerHit        EventType = "player-hit"
+	PlayerDestroyed  EventType = "player-destroyed"
+	SelfDestructed   EventType = "self-destructed"
+	BatterySpawned   EventType = "battery-spawned"
+	BatteryCollected EventType = "battery-collected"
+	BatteryDestroyed EventType = "battery-destroyed"
+)
+
+// Event is something that happened during a tick. Owner is the player it
+// happened to and Source is the player responsible for it, when there are
+// such players. Amount is the damage dealt for PlayerHit events.
+type Event struct {
+	Type   EventType
+	Coord  grid.Coord
+	Owner  grid.Owner
+	Source grid.Owner
+	Amount int
+}
+
+// Snapshot is the complete state of a game in between ticks. The game server
+// runs its games by ticking a Snapshot, so bots can use Next to look ahead
+// with exactly the same rules.
+type Snapshot struct {
+	Turn       int
+	Grid       *grid.Grid
+	Players    []*Player
+	Lasers     []*Laser
+	Batteries  []Pickup
+	Mines      []Mine
+	explosions []grid.Coord
+
+	// Map is where players spawn and batteries show up, if the game is
+	// played on a map that says. It never changes, so clones share it.
+	Map *grid.Map
+}
+
+// NewSnapshot returns the snapshot of a game on g that nobody has joined yet.
+// Any hazards on g stay put underneath whatever goes over them.
+func NewSnapshot(g *grid.Grid) *Snapshot {
+	return &Snapshot{Grid: g, Map: &grid.Map{Grid: g.Clone()}}
+}
+
+// NewMapSnapshot returns the snapshot of a game on m that nobody has joined
+// yet.
+func NewMapSnapshot(m *grid.Map) *Snapshot {
+	return &Snapshot{Grid: m.Grid.Clone(), Map: m}
+}
+
+// Clone returns a deep copy of s.
+func (s *Snapshot) Clone() *Snapshot {
+	clone := &Snapshot{
+		Turn:       s.Turn,
+		Grid:       s.Grid.Clone(),
+		Players:    make([]*Player, 0, len(s.Players)),
+		Lasers:     make([]*Laser, 0, len(s.Lasers)),
+		Batteries:  append([]Pickup(nil), s.Batteries...),
+		Mines:      append([]Mine(nil), s.Mines...),
+		Map:        s.Map,
+		explosions: append([]grid.Coord(nil), s.explosions...),
+	}
+	for _, player := range s.Players {
+		player_copy := *player
+		clone.Players = append(clone.Players, &player_copy)
+	}
+	for _, laser := range s.Lasers {
+		laser_copy := *laser
+		clone.Lasers = append(clone.Lasers, &laser_copy)
+	}
+	return clone
+}
+
+// AddPlayer places a new player on the next spawn point of the map, or on
+// a random empty cell if the map doesn't have one free.
+func (s *Snapshot) AddPlayer(config *Config, moniker string,
+	rng *rand.Rand) (*Player, error) {
+	return s.AddHandicappedPlayer(config, moniker, Handicap{}, rng)
+}
+
+// AddHandicappedPlayer is like AddPlayer, but the player starts out the way
+// handicap says instead of the way config does.
+func (s *Snapshot) AddHandicappedPlayer(config *Config, moniker string,
+	handicap Handicap, rng *rand.Rand) (*Player, error) {
+	player := &Player{
+		Moniker:     moniker,
+		Owner:       grid.Owner(len(s.Players) + 1),
+		Health:      pick(handicap.Health, config.PlayerHealth),
+		MaxHealth:   pick(handicap.MaxHealth, config.MaxPlayerHealth),
+		Energy:      pick(handicap.Energy, config.PlayerEnergy),
+		MaxEnergy:   pick(handicap.MaxEnergy, config.MaxPlayerEnergy),
+		TurnTimeout: config.TurnTimeout,
+	}
+	if handicap.TurnTimeout != nil {
+		player.TurnTimeout = *handicap.TurnTimeout
+	}
+	if handicap.Health == nil && player.Health > player.MaxHealth {
+		player.Health = player.MaxHealth
+	}
+	if handicap.Energy == nil && player.Energy > player.MaxEnergy {
+		player.Energy = player.MaxEnergy
+	}
+	if player.Health > player.MaxHealth || player.Energy > player.MaxEnergy {
+		return nil, HandicapError.New("can't start out with more than the max")
+	}
+
+	if handicap.Spawn != nil {
+		coord := *handicap.Spawn
+		if coord.X < 0 || coord.Y < 0 || coord.X >= s.Grid.Width() ||
+			coord.Y >= s.Grid.Height() {
+			return nil, HandicapError.New("spawn %s is off the grid", coord)
+		}
+		if s.Grid.CellAt(coord).Type != grid.Empty || s.occupied()[coord] {
+			return nil, HandicapError.New("spawn %s isn't free", coord)
+		}
+		player.Coord, player.Orientation = coord, grid.North
+	} else {
+		coord, orientation, ok := s.spawnPoint()
+		if !ok {
+			orientation = grid.North
+			coord, ok = s.randomEmptyCell(rng)
+		}
+		if !ok {
+			return nil, JoinError.New(
+				"grid does not have enough empty cells to place a player")
+		}
+		player.Coord, player.Orientation = coord, orientation
+	}
+	s.Players = append(s.Players, player)
+	s.Grid.SetCell(player.Coord, player.ToCell())
+	return player, nil
+}
+
+// pick returns what value points to, or fallback if value is nil.
+func pick(value *int, fallback int) int {
+	if value == nil {
+		return fallback
+	}
+	return *value
+}
+
+// Next returns the snapshot after a whole turn in which each player takes
+// the action in actions, along with everything that happened during the
+// turn. Players without an action do nothing. s itself is left untouched.
+// Ties, like two tanks driving into the same cell, and battery spawns are
+// decided with rng, so given the same rng state the result is exactly what
+// the game server would do.
+func (s *Snapshot) Next(config *Config, actions map[grid.Owner]Command,
+	rng *rand.Rand) (next *Snapshot, events []Event) {
+	next = s.Clone()
+	events = next.turn(config, actions, rng, nil)
+	return next, events
+}
+
+// turn plays a whole turn on s itself, calling tick after every tick, if it
+// isn't nil, so the game can be drawn as it goes. Both Next and the game
+// server play their turns with it, so they always play by the same rules.
+func (s *Snapshot) turn(config *Config, actions map[grid.Owner]Command,
+	rng *rand.Rand, tick func()) (events []Event) {
+	turn_ticks := 1
+	if config.TurnTicks > 0 {
+		turn_ticks = config.TurnTicks
+	}
+	for i := 0; !s.Done() && i < turn_ticks; i++ {
+		events = append(events, s.Tick(config, i == 0, actions, rng)...)
+		s.setObjects()
+		if tick != nil {
+			tick()
+		}
+	}
+	s.Turn++
+	return events
+}
+
+// Tick advances s by a single game tick. Player actions only happen on the
+// first tick of a turn; the others just move lasers along. Most callers want
+// Next instead, which takes care of a whole turn.
+func (s *Snapshot) Tick(config *Config, first bool,
+	actions map[grid.Owner]Command, rng *rand.Rand) (events []Event) {
+	s.clearObjects()
+
+	// clear explosions
+	s.explosions = s.explosions[:0]
+
+	// Do player actions. After the first tick of a turn, the only thing
+	// anybody does is keep driving, if they have a speed power-up.
+	if first || s.speeding(actions) {
+		if first {
+			for _, player := range s.Players {
+				was_alive := player.Alive()
+				if !player.Hit(config.HealthLoss) {
+					s.newExplosion(player.Coord)
+					if was_alive {
+						events = append(events, Event{Type: PlayerDestroyed,
+							Coord: player.Coord, Owner: player.Owner})
+					}
+				}
+			}
+		}
+
+		// moves are reconciled in the order they were first attempted so
+		// that ties are only ever decided by rng
+		player_moves := map[grid.Coord][]*Player{}
+		var move_order []grid.Coord
+		// which way players are driving, which isn't always the way they
+		// are facing
+		move_headings := map[*Player]grid.Orientation{}
+		for _, player := range s.Players {
+			command, ok := actions[player.Owner]
+			if !ok || !player.Alive() {
+				continue
+			}
+			if !first && !(player.Speed > 0 && isMove(command)) {
+				continue
+			}
+
+			switch command {
+			case Noop:
+			case selfDestruct:
+				player.Health = 0
+				s.newExplosion(player.Coord)
+				events = append(events, Event{Type: SelfDestructed,
+					Coord: player.Coord, Owner: player.Owner})
+			case MoveForward, Reverse, StrafeLeft, StrafeRight:
+				heading, cost, ok := moveHeading(config, player, command)
+				if !first {
+					// driving on with speed doesn't cost anything more
+					cost = 0
+				}
+				if !ok || player.Energy < cost {
+					break
+				}
+				if player.Stuck {
+					// it takes the whole move to get going off of slow
+					// ground
+					player.Stuck = false
+					break
+				}
+				// going through a portal, target_coord is where the tank
+				// comes out
+				target_cell, target_coord := s.Grid.CellRelativeTo(player.Coord,
+					heading)
+				if !target_cell.Blocks() {
+					target_coord = s.slide(target_cell, target_coord, heading)
+					player.Energy -= cost
+					if player_moves[target_coord] == nil {
+						move_order = append(move_order, target_coord)
+					}
+					player_moves[target_coord] = append(player_moves[target_coord], player)
+					move_headings[player] = heading
+				}
+			case RotateLeft:
+				player.Orientation.RotateLeft()
+			case RotateRight:
+				player.Orientation.RotateRight()
+			case FireLaser:
+				if player.CanFire() && player.Energy >= config.LaserEnergy {
+					player.Energy -= config.LaserEnergy
+					events = append(events, s.fire(config, player,
+						player.Orientation, false))
+					events = append(events, heatUp(config, player, 1)...)
+				}
+			case ChargedShot:
+				if config.Charge && player.CanFire() &&
+					player.Energy >= config.ChargeEnergy {
+					player.Energy -= config.ChargeEnergy
+					events = append(events, s.fire(config, player,
+						player.Orientation, true))
+					events = append(events, heatUp(config, player, 1)...)
+				}
+			case SpreadShot:
+				if config.Spread && player.CanFire() &&
+					player.Energy >= config.SpreadEnergy {
+					player.Energy -= config.SpreadEnergy
+					for _, o := range []grid.Orientation{
+						player.Orientation.Left(), player.Orientation,
+						player.Orientation.Right()} {
+						events = append(events, s.fire(config, player, o, false))
+					}
+					events = append(events, heatUp(config, player, 3)...)
+				}
+			case DropMine:
+				events = append(events, s.dropMine(config, player)...)
+			case Shield:
+				if config.ShieldTicks > 0 &&
+					player.Energy >= config.ShieldEnergy {
+					player.Energy -= config.ShieldEnergy
+					player.Shield = config.ShieldTicks
+					events = append(events, Event{Type: ShieldRaised,
+						Coord: player.Coord, Owner: player.Owner})
+				}
+			}
+		}
+
+		// reconcile the player moves
+	player_check:
+		for _, coord := range move_order {
+			players := player_moves[coord]
+
+			// make sure another player doesn't already occupy the spot
+			for _, player := range s.Players {
+				if player.Alive() && player.Coord == coord {
+					continue player_check
+				}
+			}
+
+			// if multiple players tried to do the same thing, let's let a random one
+			// win
+			player := players[rng.Intn(len(players))]
+
+			// did they run into a laser that is heading towards them?
+			for i, laser := range s.Lasers {
+				if !(laser.Coord == coord &&
+					laser.Orientation == move_headings[player].Opposite()) {
+					continue
+				}
+
+				s.Lasers = append(s.Lasers[:i], s.Lasers[i+1:]...)
+				events = append(events, s.laserHit(config, player, laser,
+					laser.Orientation, coord)...)
+				s.newExplosion(coord)
+				break
+			}
+
+			// did they pick up a battery?
+			for i, battery := range s.Batteries {
+				if battery.Coord != coord {
+					continue
+				}
+				s.Batteries = append(s.Batteries[:i], s.Batteries[i+1:]...)
+				player.collect(config, battery)
+				events = append(events, Event{Type: BatteryCollected,
+					Coord: coord, Owner: player.Owner})
+				break
+			}
+
+			// did they drive onto somebody else's mine?
+			for i, mine := range s.Mines {
+				if mine.Coord != coord || mine.Owner == player.Owner ||
+					s.friendly(config, mine.Owner, player) {
+					continue
+				}
+				s.Mines = append(s.Mines[:i], s.Mines[i+1:]...)
+				events = append(events, Event{Type: MineDetonated,
+					Coord: coord, Owner: player.Owner, Source: mine.Owner})
+				events = append(events, s.hit(player, config.MineDamage,
+					mine.Owner, coord)...)
+				s.newExplosion(coord)
+				break
+			}
+			player.Coord = coord
+			player.Stuck = s.terrain(coord).Type == grid.Slow
+		}
+	}
+
+	// Fire/expire lasers
+	laser_moves := map[grid.Coord][]*Laser{}
+	var laser_order []grid.Coord
+	// which way lasers that bounce off mirrors on their way will be heading
+	laser_headings := map[*Laser]grid.Orientation{}
+	var brick_hits []Event
+	for _, laser := range s.Lasers {
+		laser.Lifetime--
+		if laser.Lifetime < 1 {
+			continue
+		}
+		target_cell, target_coord, heading, ok := s.Grid.LaserStep(
+			laser.Coord, laser.Orientation)
+		if !ok {
+			// fizzled out at the edge of a bounded grid
+			continue
+		}
+		if !target_cell.Solid() {
+			if laser_moves[target_coord] == nil {
+				laser_order = append(laser_order, target_coord)
+			}
+			laser_moves[target_coord] = append(laser_moves[target_coord],
+				laser)
+			laser_headings[laser] = heading
+		} else {
+			// laser hit a wall
+			s.newExplosion(target_coord)
+			hit := Event{Type: LaserHitWall, Coord: target_coord,
+				Source: laser.Owner}
+			events = append(events, hit)
+			if target_cell.Type == grid.Brick {
+				brick_hits = append(brick_hits, hit)
+			}
+		}
+	}
+	// bricks only crumble once every laser has moved, so lasers behind one
+	// that knocks a brick out this tick don't slip through it
+	for _, hit := range brick_hits {
+		cell := s.Grid.CellAt(hit.Coord)
+		if cell.Type != grid.Brick || config.BrickHealth <= 0 {
+			continue
+		}
+		cell.Damage++
+		if cell.Damage < config.BrickHealth {
+			s.Grid.SetCell(hit.Coord, cell)
+			continue
+		}
+		s.Grid.ClearCell(hit.Coord)
+		events = append(events, Event{Type: BrickDestroyed, Coord: hit.Coord,
+			Source: hit.Source})
+	}
+	existing_lasers := append([]*Laser(nil), s.Lasers...)
+	s.Lasers = s.Lasers[:0]
+laser_check:
+	for _, coord := range laser_order {
+		lasers := laser_moves[coord]
+		if len(lasers) != 1 {
+			// lasers collided, neither one lives... put an explosion. a
+			// charged shot plows right through regular lasers, though, as
+			// long as it is the only one
+			s.newExplosion(coord)
+			events = append(events, Event{Type: LasersCollided, Coord: coord})
+			lasers = chargedLasers(lasers)
+			if len(lasers) != 1 {
+				continue
+			}
+		}
+		laser := lasers[0]
+		heading := laser_headings[laser]
+
+		// see if the laser overlaps another laser. the other laser is headed
+		// back the way this one came, mirrors and all, if it is headed the
+		// opposite way from how this one arrives
+		for _, other_laser := range existing_lasers {
+			if other_laser.Coord == coord &&
+				other_laser.Orientation.Opposite() == heading {
+				events = append(events, Event{Type: LasersCollided, Coord: coord})
+				if other_laser.Charged && !laser.Charged {
+					// plowed through by a charged shot
+					continue laser_check
+				}
+				// cull the other laser out of the laser_moves list, since it
+				// is now dead.
+				for j, laser2 := range laser_moves[laser.Coord] {
+					if laser2 == other_laser {
+						laser_moves[laser.Coord] = append(
+							laser_moves[laser.Coord][:j],
+							laser_moves[laser.Coord][j+1:]...)
+						break
+					}
+				}
+				if laser.Charged && !other_laser.Charged {
+					break
+				}
+				continue laser_check
+			}
+		}
+
+		// see if the laser hits a player
+		for _, player := range s.Players {
+			if !(player.Alive() && player.Coord == coord) {
+				continue
+			}
+			events = append(events, s.laserHit(config, player, laser,
+				heading, coord)...)
+			s.newExplosion(coord)
+			continue laser_check
+		}
+
+		// see if the laser hits a battery
+		for i, battery := range s.Batteries {
+			if battery.Coord == coord {
+				s.Batteries = append(s.Batteries[:i], s.Batteries[i+1:]...)
+				s.newExplosion(coord)
+				events = append(events, Event{Type: BatteryDestroyed,
+					Coord: coord, Source: laser.Owner})
+				continue laser_check
+			}
+		}
+
+		// see if the laser hits a mine
+		for i, mine := range s.Mines {
+			if mine.Coord == coord {
+				s.Mines = append(s.Mines[:i], s.Mines[i+1:]...)
+				s.newExplosion(coord)
+				events = append(events, Event{Type: MineDestroyed,
+					Coord: coord, Owner: mine.Owner, Source: laser.Owner})
+				continue laser_check
+			}
+		}
+
+		// laser didn't hit anything... move it
+		laser.Coord = coord
+		laser.Orientation = heading
+		s.Lasers = append(s.Lasers, laser)
+	}
+
+	// Spawn battery packs
+	if first && config.BatteryTicks > 0 && s.Turn%config.BatteryTicks == 0 {
+		// Make sure we're not exceeding the max number of batteries
+		if config.MaxBatteries < 0 ||
+			s.countPickups(grid.Battery) < config.MaxBatteries {
+			if coord, ok := s.batteryCell(rng); ok {
+				s.Batteries = append(s.Batteries, Pickup{
+					Coord: coord,
+				})
+				events = append(events, Event{Type: BatterySpawned,
+					Coord: coord})
+			}
+		}
+	}
+
+	// and every other kind of pickup
+	for _, kind := range pickupKinds {
+		pickup, _ := config.Pickup(kind)
+		if !first || pickup.Ticks <= 0 || s.Turn%pickup.Ticks != 0 {
+			continue
+		}
+		if pickup.Max >= 0 && s.countPickups(kind) >= pickup.Max {
+			continue
+		}
+		if coord, ok := s.batteryCell(rng); ok {
+			s.Batteries = append(s.Batteries, Pickup{
+				Coord: coord,
+				Kind:  kind,
+			})
+			events = append(events, Event{Type: BatterySpawned,
+				Coord: coord})
+		}
+	}
+
+	// burn anybody sitting in lava
+	for _, player := range s.Players {
+		if player.Alive() && config.LavaDamage > 0 &&
+			s.terrain(player.Coord).Type == grid.Lava {
+			events = append(events, s.hit(player, config.LavaDamage,
+				grid.None, player.Coord)...)
+			if !player.Alive() {
+				s.newExplosion(player.Coord)
+			}
+		}
+	}
+
+	for _, player := range s.Players {
+		if player.Shield > 0 {
+			player.Shield--
+		}
+		if player.Cooldown > 0 {
+			player.Cooldown--
+		}
+		if player.DoubleDamage > 0 {
+			player.DoubleDamage--
+		}
+		if player.Speed > 0 {
+			player.Speed--
+		}
+		player.Heat -= config.HeatLoss
+		if player.Heat < 0 {
+			player.Heat = 0
+		}
+	}
+	return events
+}
+
+// terrain returns what is underneath whatever is at coord now: the hazard
+// the map has there, if any, and otherwise an empty cell. Nothing that moves
+// around ever sits on anything else. Without a map, the grid itself is all
+// there is to go by, which only shows hazards that nothing is on.
+func (s *Snapshot) terrain(coord grid.Coord) grid.Cell {
+	terrain := s.Grid
+	if s.Map != nil {
+		terrain = s.Map.Grid
+	}
+	if cell := terrain.CellAt(coord); cell.Hazard() {
+		return cell
+	}
+	return grid.EmptyCell
+}
+
+// slide returns where a tank driving onto cell at coord, heading towards
+// heading, ends up. That is coord itself, unless cell is ice and the tank
+// can slide on across it to the next cell.
+func (s *Snapshot) slide(cell grid.Cell, coord grid.Coord,
+	heading grid.Orientation) grid.Coord {
+	if cell.Type != grid.Ice {
+		return coord
+	}
+	for _, player := range s.Players {
+		if player.Alive() && player.Coord == coord {
+			// bumped into somebody sitting on the ice
+			return coord
+		}
+	}
+	next_cell, next := s.Grid.CellRelativeTo(coord, heading)
+	if next_cell.Blocks() || next_cell.Type == grid.Ice {
+		return coord
+	}
+	return next
+}
+
+// pickupKinds are the kinds of pickups other than batteries, in the order
+// they spawn in.
+var pickupKinds = []grid.Type{grid.EnergyCell, grid.RepairKit,
+	grid.DoubleDamage, grid.Speed}
+
+// countPickups returns how many pickups of type kind are around.
+func (s *Snapshot) countPickups(kind grid.Type) (count int) {
+	for _, battery := range s.Batteries {
+		if battery.kind() == kind {
+			count++
+		}
+	}
+	return count
+}
+
+// speeding says whether any tank with a speed power-up is driving this turn.
+func (s *Snapshot) speeding(actions map[grid.Owner]Command) bool {
+	for _, player := range s.Players {
+		if player.Alive() && player.Speed > 0 &&
+			isMove(actions[player.Owner]) {
+			return true
+		}
+	}
+	return false
+}
+
+func isMove(command Command) bool {
+	switch command {
+	case MoveForward, Reverse, StrafeLeft, StrafeRight:
+		return true
+	}
+	return false
+}
+
+// moveHeading returns which way player drives for command, and how much
+// energy it takes. ok is false if config doesn't allow driving that way.
+func moveHeading(config *Config, player *Player, command Command) (
+	heading grid.Orientation, cost int, ok bool) {
+	switch command {
+	case MoveForward:
+		return player.Orientation, 0, true
+	case Reverse:
+		return player.Orientation.Opposite(), config.ReverseEnergy,
+			config.Reverse
+	case StrafeLeft:
+		return player.Orientation.Left(), config.StrafeEnergy, config.Strafe
+	case StrafeRight:
+		return player.Orientation.Right(), config.StrafeEnergy, config.Strafe
+	}
+	return player.Orientation, 0, false
+}
+
+// laserHit is laser, heading towards heading, hitting player, unless the
+// player's shield is in the way.
+func (s *Snapshot) laserHit(config *Config, player *Player, laser *Laser,
+	heading grid.Orientation, coord grid.Coord) []Event {
+	if player.Shielded(config, heading) {
+		return []Event{{Type: ShieldBlocked, Coord: coord,
+			Owner: player.Owner, Source: laser.Owner}}
+	}
+	if s.friendly(config, laser.Owner, player) {
+		return nil
+	}
+	return s.hit(player, laser.Damage(config), laser.Owner, coord)
+}
+
+// friendly says whether lasers and mines belonging to owner can't hurt
+// player because they are on the same team.
+func (s *Snapshot) friendly(config *Config, owner grid.Owner,
+	player *Player) bool {
+	if config.FriendlyFire {
+		return false
+	}
+	other := s.FindPlayer(owner)
+	return other != nil && player.Ally(other)
+}
+
+// allies returns the owners of player's allies.
+func (s *Snapshot) allies(player *Player) (owners []grid.Owner) {
+	for _, other := range s.Players {
+		if player.Ally(other) {
+			owners = append(owners, other.Owner)
+		}
+	}
+	return owners
+}
+
+// fire adds a laser heading towards o starting at player's coordinates...
+// it will move when lasers are handled with in Tick. The lifetime is the
+// default lifetime + 1 since it will be decremented there.
+func (s *Snapshot) fire(config *Config, player *Player, o grid.Orientation,
+	charged bool) Event {
+	lifetime := config.LaserLifetime
+	if charged {
+		lifetime = config.ChargeDistance
+	}
+	s.Lasers = append(s.Lasers, &Laser{
+		Coord:       player.Coord,
+		Lifetime:    lifetime + 1,
+		Owner:       player.Owner,
+		Orientation: o,
+		Charged:     charged,
+		Doubled:     player.DoubleDamage > 0,
+	})
+	return Event{Type: LaserFired, Coord: player.Coord, Owner: player.Owner}
+}
+
+// heatUp adds the heat of firing shots lasers to player. A player that gets
+// to MaxHeat overheats, losing all of its heat but not being able to fire
+// again for OverheatTicks.
+func heatUp(config *Config, player *Player, shots int) []Event {
+	if config.ShotHeat <= 0 {
+		return nil
+	}
+	player.Heat += config.ShotHeat * shots
+	if player.Heat < config.MaxHeat {
+		return nil
+	}
+	player.Heat = 0
+	player.Cooldown = config.OverheatTicks
+	return []Event{{Type: Overheated, Coord: player.Coord,
+		Owner: player.Owner}}
+}
+
+func chargedLasers(lasers []*Laser) (charged []*Laser) {
+	for _, laser := range lasers {
+		if laser.Charged {
+			charged = append(charged, laser)
+		}
+	}
+	return charged
+}
+
+// dropMine leaves a mine on the cell behind player, if the game has mines,
+// the player can afford it, hasn't dropped as many as it is allowed already,
+// and nothing is in the way there.
+func (s *Snapshot) dropMine(config *Config, player *Player) []Event {
+	if !config.Mines || player.Energy < config.MineEnergy {
+		return nil
+	}
+	if config.MaxMines >= 0 {
+		count := 0
+		for _, mine := range s.Mines {
+			if mine.Owner == player.Owner {
+				count++
+			}
+		}
+		if count >= config.MaxMines {
+			return nil
+		}
+	}
+	cell, coord := s.Grid.CellRelativeTo(player.Coord,
+		player.Orientation.Opposite())
+	if cell.Type != grid.Empty || coord == player.Coord ||
+		s.occupied()[coord] {
+		return nil
+	}
+	player.Energy -= config.MineEnergy
+	s.Mines = append(s.Mines, Mine{Coord: coord, Owner: player.Owner})
+	return []Event{{Type: MineDropped, Coord: coord, Owner: player.Owner}}
+}
+
+func (s *Snapshot) hit(player *Player, damage int, source grid.Owner,
+	coord grid.Coord) (events []Event) {
+	events = append(events, Event{Type: PlayerHit, Coord: coord,
+		Owner: player.Owner, Source: source, Amount: damage})
+	if !player.Hit(damage) {
+		events = append(events, Event{Type: PlayerDestroyed, Coord: coord,
+			Owner: player.Owner, Source: source})
+	}
+	return events
+}
+
+// TurnState returns what the player owned by owner gets told about s.
+func (s *Snapshot) TurnState(owner grid.Owner) TurnState {
+	player := s.FindPlayer(owner)
+	if player == nil {
+		return TurnState{}
+	}
+	return TurnState{
+		Status:      s.PlayerStatus(player),
+		Health:      player.Health,
+		Energy:      player.Energy,
+		Orientation: player.Orientation,
+		Shield:      player.Shield,
+		Heat:        player.Heat,
+		Cooldown:    player.Cooldown,
+		Stuck:       player.Stuck,
+		PowerUps: PowerUps{
+			DoubleDamage: player.DoubleDamage,
+			Speed:        player.Speed,
+		},
+		Team: player.Team,
+		Grid: s.Grid.SerializeFor(player.Owner, s.allies(player)...),
+	}
+}
+
+// Done says whether the game is over, with only one tank, or tanks from only
+// one team, left.
+func (s *Snapshot) Done() bool {
+	return s.aliveSides() < 2
+}
+
+// aliveSides counts the teams that still have tanks alive, counting every
+// tank that isn't on a team as a team of its own.
+func (s *Snapshot) aliveSides() (count int) {
+	teams := map[int]bool{}
+	for _, player := range s.Players {
+		if !player.Alive() || teams[player.Team] {
+			continue
+		}
+		if player.Team != 0 {
+			teams[player.Team] = true
+		}
+		count++
+	}
+	return count
+}
+
+// sideAlive says whether target, or anybody on its team, is still alive.
+func (s *Snapshot) sideAlive(target *Player) bool {
+	if target.Alive() {
+		return true
+	}
+	for _, player := range s.Players {
+		if player.Alive() && target.Ally(player) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Snapshot) AliveCount() (count int) {
+	for _, player := range s.Players {
+		if player.Alive() {
+			count++
+		}
+	}
+	return count
+}
+
+// Winner returns the last player standing once the game is over, or one of
+// the players left on the last team standing. winner is nil if the game
+// ended in a draw.
+func (s *Snapshot) Winner() (winner *Player, ok bool) {
+	if !s.Done() {
+		return nil, false
+	}
+	for _, player := range s.Players {
+		if player.Alive() {
+			return player, true
+		}
+	}
+	return nil, true
+}
+
+// PlayerStatus returns how the game is going for target. A team wins
+// together, including teammates that didn't make it.
+func (s *Snapshot) PlayerStatus(target *Player) GameStatus {
+	sides := s.aliveSides()
+	switch {
+	case sides == 0:
+		return Draw
+	case sides > 1:
+		return Running
+	case s.sideAlive(target):
+		return Won
+	default:
+		return Lost
+	}
+}
+
+func (s *Snapshot) FindPlayer(owner grid.Owner) *Player {
+	for _, player := range s.Players {
+		if player.Owner == owner {
+			return player
+		}
+	}
+	return nil
+}
+
+// spawnPoint returns the map's spawn for the next player to join, if it has
+// one and nothing is in the way on it.
+func (s *Snapshot) spawnPoint() (grid.Coord, grid.Orientation, bool) {
+	if s.Map == nil || len(s.Players) >= len(s.Map.Spawns) {
+		return grid.Coord{}, grid.North, false
+	}
+	spawn := s.Map.Spawns[len(s.Players)]
+	if s.occupied()[spawn.Coord] {
+		return grid.Coord{}, grid.North, false
+	}
+	return spawn.Coord, spawn.Orientation, true
+}
+
+// occupied returns the cells something is in the way on.
+func (s *Snapshot) occupied() map[grid.Coord]bool {
+	nogood := map[grid.Coord]bool{}
+	for _, player := range s.Players {
+		if player.Alive() {
+			nogood[player.Coord] = true
+		}
+	}
+	for _, battery := range s.Batteries {
+		nogood[battery.Coord] = true
+	}
+	for _, laser := range s.Lasers {
+		nogood[laser.Coord] = true
+	}
+	for _, mine := range s.Mines {
+		nogood[mine.Coord] = true
+	}
+	return nogood
+}
+
+func (s *Snapshot) randomEmptyCell(rng *rand.Rand) (grid.Coord, bool) {
+	candidates := make([]grid.Coord, 0, s.Grid.Width()*s.Grid.Height())
+	for y := 0; y < s.Grid.Height(); y++ {
+		for x := 0; x < s.Grid.Width(); x++ {
+			candidates = append(candidates, grid.Coord{X: x, Y: y})
+		}
+	}
+	return s.randomFreeCell(rng, candidates)
+}
+
+// randomFreeCell picks one of candidates that is empty and that nothing is
+// in the way on.
+func (s *Snapshot) randomFreeCell(rng *rand.Rand, candidates []grid.Coord) (
+	grid.Coord, bool) {
+	nogood := s.occupied()
+	free := make([]grid.Coord, 0, len(candidates))
+	for _, coord := range candidates {
+		if s.Grid.CellAt(coord).Type != grid.Empty || nogood[coord] {
+			continue
+		}
+		free = append(free, coord)
+	}
+
+	if len(free) == 0 {
+		return grid.Coord{}, false
+	}
+	return free[rng.Intn(len(free))], true
+}
+
+// batteryCell picks where the next battery goes. On maps with battery
+// spawners or zones, batteries only ever show up on those: on an empty
+// spawner if there is one, and otherwise somewhere in a zone. Anywhere else,
+// they show up on any empty cell.
+func (s *Snapshot) batteryCell(rng *rand.Rand) (grid.Coord, bool) {
+	if s.Map == nil ||
+		len(s.Map.BatterySpawners)+len(s.Map.BatteryZones) == 0 {
+		return s.randomEmptyCell(rng)
+	}
+	if coord, ok := s.randomFreeCell(rng, s.Map.BatterySpawners); ok {
+		return coord, true
+	}
+	return s.randomFreeCell(rng, s.Map.BatteryZones)
+}
+
+func (s *Snapshot) clearObjects() {
+	// Clear all tracked objects; they will be placed again after all actions
+	// are resolved
+	for _, player := range s.Players {
+		if player.Alive() {
+			s.Grid.SetCell(player.Coord, s.terrain(player.Coord))
+		}
+	}
+	for _, laser := range s.Lasers {
+		s.Grid.SetCell(laser.Coord, s.terrain(laser.Coord))
+	}
+	for _, battery := range s.Batteries {
+		s.Grid.SetCell(battery.Coord, s.terrain(battery.Coord))
+	}
+	for _, mine := range s.Mines {
+		s.Grid.SetCell(mine.Coord, s.terrain(mine.Coord))
+	}
+	for _, explosion_coord := range s.explosions {
+		s.Grid.SetCellExploding(explosion_coord, false)
+	}
+}
+
+func (s *Snapshot) setObjects() {
+	// Place all tracked objects in order of rendering priority
+	for _, battery := range s.Batteries {
+		s.Grid.SetCell(battery.Coord, battery.ToCell())
+	}
+	for _, mine := range s.Mines {
+		s.Grid.SetCell(mine.Coord, mine.ToCell())
+	}
+	for _, laser := range s.Lasers {
+		s.Grid.SetCell(laser.Coord, laser.ToCell())
+	}
+	for _, player := range s.Players {
+		if player.Alive() {
+			s.Grid.SetCell(player.Coord, player.ToCell())
+		}
+	}
+	// add explosions
+	for _, explosion_coord := range s.explosions {
+		s.Grid.SetCellExploding(explosion_coord, true)
+	}
+
+}
+
+func (s *Snapshot) newExplosion(coord grid.Coord) {
+	s.explosions = append(s.explosions, coord)
+}
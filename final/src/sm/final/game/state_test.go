@@ -0,0 +1,244 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package game
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"sm/final/grid"
+)
+
+// testConfig returns the default rules, minus anything that happens on its
+// own, so tests only see what their actions do.
+func testConfig() *Config {
+	config := DefaultConfig()
+	config.TurnTicks = 1
+	config.HealthLoss = 0
+	config.BatteryTicks = 0
+	return config
+}
+
+// newTestSnapshot returns a snapshot of the map in rows, with a player on
+// every spawn point. Player n is owner n, in reading order.
+func newTestSnapshot(t *testing.T, config *Config, rows ...string) *Snapshot {
+	m, err := grid.ReadMap(strings.NewReader(strings.Join(rows, "\n") + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewMapSnapshot(m)
+	for i := range m.Spawns {
+		_, err := s.AddPlayer(config, fmt.Sprintf("player%d", i+1), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+// tickTest plays turns on a map, one set of actions per turn, and then
+// checks the outcome. check returns what is wrong, or "" if nothing is.
+type tickTest struct {
+	name   string
+	rows   []string
+	config func(config *Config)
+	setup  func(s *Snapshot)
+	turns  []map[grid.Owner]Command
+	check  func(s *Snapshot, events []Event) string
+}
+
+func runTickTests(t *testing.T, tests []tickTest) {
+	for _, test := range tests {
+		config := testConfig()
+		if test.config != nil {
+			test.config(config)
+		}
+		s := newTestSnapshot(t, config, test.rows...)
+		if test.setup != nil {
+			test.setup(s)
+		}
+		rng := rand.New(rand.NewSource(1))
+		var events []Event
+		for _, actions := range test.turns {
+			events = append(events, s.turn(config, actions, rng, nil)...)
+		}
+		if problem := test.check(s, events); problem != "" {
+			t.Errorf("%s: %s", test.name, problem)
+		}
+	}
+}
+
+// expectPlayer checks where player owner is, which way it faces and how much
+// health and energy it has left.
+func expectPlayer(s *Snapshot, owner grid.Owner, x, y int,
+	orientation grid.Orientation, health, energy int) string {
+	player := s.FindPlayer(owner)
+	got := fmt.Sprintf("at (%d,%d) facing %s with %d health and %d energy",
+		player.Coord.X, player.Coord.Y, player.Orientation, player.Health,
+		player.Energy)
+	want := fmt.Sprintf("at (%d,%d) facing %s with %d health and %d energy",
+		x, y, orientation, health, energy)
+	if got != want {
+		return fmt.Sprintf("player %d is %s, not %s", owner, got, want)
+	}
+	return ""
+}
+
+// expectHealth checks how much health player owner has left.
+func expectHealth(s *Snapshot, owner grid.Owner, health int) string {
+	if got := s.FindPlayer(owner).Health; got != health {
+		return fmt.Sprintf("player %d has %d health, not %d", owner, got,
+			health)
+	}
+	return ""
+}
+
+// countEvents returns how many of events are of type event_type.
+func countEvents(events []Event, event_type EventType) (count int) {
+	for _, event := range events {
+		if event.Type == event_type {
+			count++
+		}
+	}
+	return count
+}
+
+func TestTickBasics(t *testing.T) {
+	config := testConfig()
+	health, energy := config.PlayerHealth, config.PlayerEnergy
+	runTickTests(t, []tickTest{
+		{
+			name:  "move forward",
+			rows:  []string{"_____", "__^__", "____v"},
+			turns: []map[grid.Owner]Command{{1: MoveForward}},
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 2, 0, grid.North, health, energy)
+			},
+		},
+		{
+			name:  "walls stop tanks",
+			rows:  []string{"__W__", "__^__", "____v"},
+			turns: []map[grid.Owner]Command{{1: MoveForward}},
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 2, 1, grid.North, health, energy)
+			},
+		},
+		{
+			name: "turning",
+			rows: []string{"_____", "__^__", "____v"},
+			turns: []map[grid.Owner]Command{{1: RotateRight},
+				{1: RotateRight}, {1: RotateLeft}},
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 2, 1, grid.East, health, energy)
+			},
+		},
+		{
+			name:  "the board wraps around",
+			rows:  []string{"__^__", "_____", "____v"},
+			turns: []map[grid.Owner]Command{{1: MoveForward}},
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 2, 2, grid.North, health, energy)
+			},
+		},
+		{
+			name:  "lasers hit tanks",
+			rows:  []string{">___<"},
+			turns: []map[grid.Owner]Command{{1: FireLaser}, nil, nil, nil},
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 2, 4, 0, grid.West,
+					health-config.LaserDamage, energy)
+			},
+		},
+		{
+			name:  "firing takes energy",
+			rows:  []string{">___<"},
+			turns: []map[grid.Owner]Command{{1: FireLaser}},
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 0, 0, grid.East, health,
+					energy-config.LaserEnergy)
+			},
+		},
+		{
+			name: "lasers meeting head on cancel out",
+			rows: []string{">____<"},
+			turns: []map[grid.Owner]Command{{1: FireLaser, 2: FireLaser},
+				nil, nil, nil, nil},
+			check: func(s *Snapshot, events []Event) string {
+				if countEvents(events, LasersCollided) == 0 {
+					return "lasers didn't collide"
+				}
+				return expectPlayer(s, 2, 5, 0, grid.West, health,
+					energy-config.LaserEnergy)
+			},
+		},
+		{
+			name:  "only one of two tanks gets the same cell",
+			rows:  []string{">_<"},
+			turns: []map[grid.Owner]Command{{1: MoveForward, 2: MoveForward}},
+			check: func(s *Snapshot, events []Event) string {
+				a, b := s.FindPlayer(1).Coord, s.FindPlayer(2).Coord
+				if a == b || (a.X != 1 && b.X != 1) {
+					return fmt.Sprintf("tanks ended up on %s and %s", a, b)
+				}
+				return ""
+			},
+		},
+		{
+			name: "batteries give energy and health",
+			rows: []string{"_____", "__^__", "____v"},
+			config: func(config *Config) {
+				config.PlayerHealth = 100
+			},
+			setup: func(s *Snapshot) {
+				s.Batteries = append(s.Batteries,
+					Pickup{Coord: grid.Coord{X: 2, Y: 0}})
+			},
+			turns: []map[grid.Owner]Command{{1: MoveForward}},
+			check: func(s *Snapshot, events []Event) string {
+				if len(s.Batteries) != 0 {
+					return "battery is still there"
+				}
+				return expectPlayer(s, 1, 2, 0, grid.North,
+					100+config.BatteryHealth, energy+config.BatteryPower)
+			},
+		},
+		{
+			name: "health runs out",
+			rows: []string{"_____", "__^__", "____v"},
+			config: func(config *Config) {
+				config.HealthLoss = 1
+			},
+			turns: []map[grid.Owner]Command{nil, nil, nil},
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 2, 1, grid.North, health-3, energy)
+			},
+		},
+	})
+}
+
+func TestNextLeavesSnapshotAlone(t *testing.T) {
+	config := testConfig()
+	s := newTestSnapshot(t, config, ">___<", "_____")
+	before := s.Grid.SerializeFor(1)
+
+	actions := map[grid.Owner]Command{1: FireLaser, 2: MoveForward}
+	next, events := s.Next(config, actions, rand.New(rand.NewSource(1)))
+	if s.Grid.SerializeFor(1) != before || len(s.Lasers) != 0 ||
+		s.Turn != 0 || s.FindPlayer(2).Coord.X != 4 {
+		t.Fatal("Next changed the snapshot it was called on")
+	}
+	if next.Turn != 1 || len(next.Lasers) != 1 ||
+		next.FindPlayer(2).Coord.X != 3 {
+		t.Fatalf("unexpected next snapshot:\n%s", next.Grid.SerializeFor(1))
+	}
+
+	// the same rng state gives the same result
+	again, again_events := s.Next(config, actions,
+		rand.New(rand.NewSource(1)))
+	if again.Grid.SerializeFor(1) != next.Grid.SerializeFor(1) ||
+		len(again_events) != len(events) {
+		t.Fatal("Next isn't deterministic")
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package game
+
+import (
+	"fmt"
+	"testing"
+
+	"sm/final/grid"
+)
+
+func TestTickTeams(t *testing.T) {
+	config := testConfig()
+	health := config.PlayerHealth
+	// players 1 and 2 are on team 1, and player 3 is on team 2
+	teams := func(s *Snapshot) {
+		for _, player := range s.Players {
+			player.Team = 1
+		}
+		s.FindPlayer(3).Team = 2
+	}
+	runTickTests(t, []tickTest{
+		{
+			name:  "lasers don't hurt teammates",
+			rows:  []string{">>__<"},
+			setup: teams,
+			turns: []map[grid.Owner]Command{{1: FireLaser}, nil},
+			check: func(s *Snapshot, events []Event) string {
+				return expectHealth(s, 2, health)
+			},
+		},
+		{
+			name: "unless friendly fire is on",
+			rows: []string{">>__<"},
+			config: func(config *Config) {
+				config.FriendlyFire = true
+			},
+			setup: teams,
+			turns: []map[grid.Owner]Command{{1: FireLaser}, nil},
+			check: func(s *Snapshot, events []Event) string {
+				return expectHealth(s, 2, health-config.LaserDamage)
+			},
+		},
+		{
+			name:  "the game goes on while both teams have tanks",
+			rows:  []string{">>__<"},
+			setup: teams,
+			turns: []map[grid.Owner]Command{nil},
+			check: func(s *Snapshot, events []Event) string {
+				if s.Done() || s.PlayerStatus(s.FindPlayer(1)) != Running {
+					return "the game ended early"
+				}
+				return ""
+			},
+		},
+		{
+			name: "a team wins together",
+			rows: []string{">>__<"},
+			setup: func(s *Snapshot) {
+				teams(s)
+				s.FindPlayer(1).Health = 0
+				s.FindPlayer(3).Health = 0
+			},
+			turns: []map[grid.Owner]Command{nil},
+			check: func(s *Snapshot, events []Event) string {
+				if winner, ok := s.Winner(); !ok || winner == nil ||
+					winner.Team != 1 {
+					return fmt.Sprintf("expected team 1 to win, got %v", winner)
+				}
+				for owner, want := range map[grid.Owner]GameStatus{
+					1: Won, 2: Won, 3: Lost} {
+					if got := s.PlayerStatus(s.FindPlayer(owner)); got != want {
+						return fmt.Sprintf("player %d: expected %v, got %v",
+							owner, want, got)
+					}
+				}
+				return ""
+			},
+		},
+		{
+			name: "tanks without a team are on their own",
+			rows: []string{">>__<"},
+			setup: func(s *Snapshot) {
+				s.FindPlayer(3).Health = 0
+			},
+			turns: []map[grid.Owner]Command{nil},
+			check: func(s *Snapshot, events []Event) string {
+				if s.Done() {
+					return "players 1 and 2 were counted as a team"
+				}
+				return ""
+			},
+		},
+	})
+}
@@ -0,0 +1,123 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package game
+
+import (
+	"fmt"
+	"testing"
+
+	"sm/final/grid"
+)
+
+func TestTickWeapons(t *testing.T) {
+	config := testConfig()
+	health, energy := config.PlayerHealth, config.PlayerEnergy
+	weapons := func(config *Config) {
+		config.Charge = true
+		config.Spread = true
+		config.Mines = true
+	}
+	shot := func(command Command) []map[grid.Owner]Command {
+		return []map[grid.Owner]Command{{1: command}, nil, nil, nil}
+	}
+	runTickTests(t, []tickTest{
+		{
+			name:  "charged shots are off by default",
+			rows:  []string{">___<"},
+			turns: shot(ChargedShot),
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 0, 0, grid.East, health, energy)
+			},
+		},
+		{
+			name:   "charged shots do charged damage",
+			rows:   []string{">___<"},
+			config: weapons,
+			turns:  shot(ChargedShot),
+			check: func(s *Snapshot, events []Event) string {
+				if problem := expectHealth(s, 2,
+					health-config.ChargeDamage); problem != "" {
+					return problem
+				}
+				return expectPlayer(s, 1, 0, 0, grid.East, health,
+					energy-config.ChargeEnergy)
+			},
+		},
+		{
+			name:   "and plow through regular lasers",
+			rows:   []string{">____<"},
+			config: weapons,
+			turns: []map[grid.Owner]Command{
+				{1: ChargedShot, 2: FireLaser}, nil, nil, nil, nil},
+			check: func(s *Snapshot, events []Event) string {
+				return expectHealth(s, 2, health-config.ChargeDamage)
+			},
+		},
+		{
+			name:  "spread shots are off by default",
+			rows:  []string{"__v__", "_____", "<_^__"},
+			turns: shot(SpreadShot),
+			check: func(s *Snapshot, events []Event) string {
+				return expectPlayer(s, 1, 2, 0, grid.South, health, energy)
+			},
+		},
+		{
+			name:   "spread shots go three ways",
+			rows:   []string{"__v<>", "_____", "__^__"},
+			config: weapons,
+			turns:  shot(SpreadShot),
+			check: func(s *Snapshot, events []Event) string {
+				for owner := grid.Owner(2); owner <= 4; owner++ {
+					problem := expectHealth(s, owner,
+						health-config.LaserDamage)
+					if problem != "" {
+						return problem
+					}
+				}
+				return expectPlayer(s, 1, 2, 0, grid.South, health,
+					energy-config.SpreadEnergy)
+			},
+		},
+		{
+			name:  "mines are off by default",
+			rows:  []string{"_>__<"},
+			turns: []map[grid.Owner]Command{{1: DropMine}},
+			check: func(s *Snapshot, events []Event) string {
+				if len(s.Mines) != 0 {
+					return "a mine got dropped"
+				}
+				return ""
+			},
+		},
+		{
+			name:   "mines go off under other tanks",
+			rows:   []string{"_>__<", "_____"},
+			config: weapons,
+			turns: []map[grid.Owner]Command{{1: DropMine}, {1: RotateLeft},
+				{1: MoveForward}, {2: MoveForward}, {2: MoveForward},
+				{2: MoveForward}, {2: MoveForward}},
+			check: func(s *Snapshot, events []Event) string {
+				if countEvents(events, MineDetonated) != 1 {
+					return "the mine didn't go off"
+				}
+				return expectHealth(s, 2, health-config.MineDamage)
+			},
+		},
+		{
+			name: "but only so many at once",
+			rows: []string{"_>__<"},
+			config: func(config *Config) {
+				weapons(config)
+				config.MaxMines = 1
+			},
+			turns: []map[grid.Owner]Command{{1: DropMine}, {1: RotateLeft},
+				{1: RotateLeft}, {1: DropMine}},
+			check: func(s *Snapshot, events []Event) string {
+				if len(s.Mines) != 1 {
+					return fmt.Sprintf("expected 1 mine, got %d", len(s.Mines))
+				}
+				return ""
+			},
+		},
+	})
+}
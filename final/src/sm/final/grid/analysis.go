@@ -0,0 +1,390 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package grid
+
+import (
+	"fmt"
+	"math"
+)
+
+// Symmetry is a way of laying a map over itself. Since the board wraps
+// around, the mirror line or center of rotation can be anywhere, not just
+// in the middle, unless the board is bounded.
+type Symmetry int
+
+const (
+	MirrorLeftRight Symmetry = 0
+	MirrorTopBottom Symmetry = 1
+	Rotate180       Symmetry = 2
+	Rotate90        Symmetry = 3
+)
+
+var Symmetries = []Symmetry{MirrorLeftRight, MirrorTopBottom, Rotate180,
+	Rotate90}
+
+func (s Symmetry) String() string {
+	switch s {
+	case MirrorLeftRight:
+		return "left-right mirror"
+	case MirrorTopBottom:
+		return "top-bottom mirror"
+	case Rotate180:
+		return "half turn"
+	case Rotate90:
+		return "quarter turn"
+	}
+	panic("unreachable")
+}
+
+// SymmetryCheck is how close a grid comes to having a symmetry. Mismatches
+// are the cells that are a wall where their counterpart isn't, or the other
+// way around, with the mirror line or center of rotation placed where the
+// fewest cells mismatch. A symmetry that can't apply to the grid at all,
+// like a quarter turn of a grid that isn't square, has every cell
+// mismatching.
+type SymmetryCheck struct {
+	Symmetry   Symmetry
+	Mismatches []Coord
+}
+
+func (c SymmetryCheck) Holds() bool {
+	return len(c.Mismatches) == 0
+}
+
+// counterpart returns where s with the given shift takes coord.
+func (g *Grid) counterpart(s Symmetry, shift, coord Coord) Coord {
+	w, h := g.Width(), g.Height()
+	switch s {
+	case MirrorLeftRight:
+		return Coord{X: mod(shift.X-coord.X, w), Y: coord.Y}
+	case MirrorTopBottom:
+		return Coord{X: coord.X, Y: mod(shift.Y-coord.Y, h)}
+	case Rotate180:
+		return Coord{X: mod(shift.X-coord.X, w), Y: mod(shift.Y-coord.Y, h)}
+	default:
+		return Coord{X: mod(shift.X-coord.Y, w), Y: mod(shift.Y+coord.X, h)}
+	}
+}
+
+// centers returns the shifts that put the mirror line or center of rotation
+// of each symmetry in the middle of the grid.
+func (g *Grid) centers() map[Symmetry]Coord {
+	return map[Symmetry]Coord{
+		MirrorLeftRight: {X: g.Width() - 1},
+		MirrorTopBottom: {Y: g.Height() - 1},
+		Rotate180:       {X: g.Width() - 1, Y: g.Height() - 1},
+		Rotate90:        {X: g.Width() - 1},
+	}
+}
+
+func mod(n, size int) int {
+	n %= size
+	if n < 0 {
+		n += size
+	}
+	return n
+}
+
+// CheckSymmetry says how close g comes to having symmetry s.
+func (g *Grid) CheckSymmetry(s Symmetry) (check SymmetryCheck) {
+	check.Symmetry = s
+	w, h := g.Width(), g.Height()
+	if s == Rotate90 && w != h {
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				check.Mismatches = append(check.Mismatches, Coord{X: x, Y: y})
+			}
+		}
+		return check
+	}
+	shifts_x, shifts_y := w, h
+	switch s {
+	case MirrorLeftRight:
+		shifts_y = 1
+	case MirrorTopBottom:
+		shifts_x = 1
+	}
+	first_x, first_y := 0, 0
+	if g.bounded {
+		// without the wrap around, only the middle of the grid will do
+		center := g.centers()[s]
+		first_x, first_y = center.X, center.Y
+		shifts_x, shifts_y = first_x+1, first_y+1
+	}
+
+	best := -1
+	for sy := first_y; sy < shifts_y; sy++ {
+		for sx := first_x; sx < shifts_x; sx++ {
+			shift := Coord{X: sx, Y: sy}
+			var mismatches []Coord
+			for y := 0; y < h; y++ {
+				for x := 0; x < w; x++ {
+					coord := Coord{X: x, Y: y}
+					other := g.counterpart(s, shift, coord)
+					if (g.cells[y][x].Type == Wall) !=
+						(g.cells[other.Y][other.X].Type == Wall) {
+						mismatches = append(mismatches, coord)
+					}
+				}
+			}
+			if best < 0 || len(mismatches) < best {
+				best = len(mismatches)
+				check.Mismatches = mismatches
+			}
+		}
+	}
+	return check
+}
+
+// SpawnScore is how good a place coord is to start a game from. Tanks far
+// from walls have room to get out of the way, and tanks in few lines of fire
+// can be shot from fewer places.
+type SpawnScore struct {
+	Coord        Coord
+	WallDistance int
+	LinesOfFire  int
+}
+
+// SpawnScores scores every empty cell of g as a place to start from, for
+// lasers that go laser_distance cells. WallDistance is -1 if g has no walls.
+func (g *Grid) SpawnScores(laser_distance int) (scores []SpawnScore) {
+	walls := g.wallDistances()
+	for y := 0; y < g.Height(); y++ {
+		for x := 0; x < g.Width(); x++ {
+			coord := Coord{X: x, Y: y}
+			if g.cells[y][x].Type != Empty {
+				continue
+			}
+			scores = append(scores, SpawnScore{
+				Coord:        coord,
+				WallDistance: walls[y][x],
+				LinesOfFire:  g.LinesOfFire(coord, laser_distance),
+			})
+		}
+	}
+	return scores
+}
+
+// LinesOfFire counts the cells a tank could shoot coord from, with lasers
+// that go laser_distance cells.
+func (g *Grid) LinesOfFire(coord Coord, laser_distance int) (count int) {
+	for o := North; o <= West; o++ {
+		current, heading := coord, o
+		for i := 0; i < laser_distance; i++ {
+			var cell Cell
+			var ok bool
+			cell, current, heading, ok = g.LaserStep(current, heading)
+			if !ok || current == coord || cell.Solid() {
+				break
+			}
+			count++
+		}
+	}
+	return count
+}
+
+func (g *Grid) wallDistances() [][]int {
+	distances := make([][]int, g.Height())
+	var queue []Coord
+	for y := range distances {
+		distances[y] = make([]int, g.Width())
+		for x := range distances[y] {
+			distances[y][x] = -1
+			if g.cells[y][x].Type == Wall {
+				distances[y][x] = 0
+				queue = append(queue, Coord{X: x, Y: y})
+			}
+		}
+	}
+	for len(queue) > 0 {
+		coord := queue[0]
+		queue = queue[1:]
+		for _, next := range g.Neighbors(coord) {
+			if distances[next.Y][next.X] < 0 {
+				distances[next.Y][next.X] = distances[coord.Y][coord.X] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+	return distances
+}
+
+// SpawnFairness scores how evenly matched tanks starting from any two of
+// scores are, from 0 to 1. It is one minus the Gini coefficient of either
+// the lines of fire or the distances to walls, whichever are spread more
+// unevenly, so it is 1 when every spawn is as good as any other.
+func SpawnFairness(scores []SpawnScore) float64 {
+	lines := make([]int, 0, len(scores))
+	walls := make([]int, 0, len(scores))
+	for _, score := range scores {
+		lines = append(lines, score.LinesOfFire)
+		walls = append(walls, score.WallDistance)
+	}
+	return 1 - math.Max(gini(lines), gini(walls))
+}
+
+// gini returns the Gini coefficient of values: the average difference
+// between any two of them, relative to twice their average.
+func gini(values []int) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	var sum, differences float64
+	for i, a := range values {
+		sum += float64(a)
+		for _, b := range values[i+1:] {
+			differences += math.Abs(float64(a - b))
+		}
+	}
+	if sum <= 0 {
+		return 0
+	}
+	// differences only counts each pair once, which saves halving it
+	return differences / (float64(len(values)) * sum)
+}
+
+// DeadEnds returns the pockets of g a tank can only get out of the way it
+// came in: dead end cells, along with the corridor leading up to each.
+func (g *Grid) DeadEnds() (pockets [][]Coord) {
+	exits := func(coord Coord) (open []Coord) {
+		for _, next := range g.Neighbors(coord) {
+			if next != coord && g.open(next, nil) {
+				open = append(open, next)
+			}
+		}
+		return open
+	}
+
+	seen := map[Coord]bool{}
+	for y := 0; y < g.Height(); y++ {
+		for x := 0; x < g.Width(); x++ {
+			coord := Coord{X: x, Y: y}
+			if seen[coord] || !g.open(coord, nil) || len(exits(coord)) != 1 {
+				continue
+			}
+			pocket := []Coord{coord}
+			seen[coord] = true
+			prev, current := coord, exits(coord)[0]
+			for !seen[current] {
+				open := exits(current)
+				if len(open) != 2 {
+					break
+				}
+				pocket = append(pocket, current)
+				seen[current] = true
+				next := open[0]
+				if next == prev {
+					next = open[1]
+				}
+				prev, current = current, next
+			}
+			pockets = append(pockets, pocket)
+		}
+	}
+	return pockets
+}
+
+// Analysis is everything Analyze found out about a map.
+type Analysis struct {
+	Width      int
+	Height     int
+	OpenCells  int
+	Components [][]Coord
+	Symmetries []SymmetryCheck
+	Spawns     []SpawnScore
+	Fairness   float64
+	DeadEnds   [][]Coord
+
+	extra []string
+}
+
+// Analyze checks g over as a map to play on, with lasers that go
+// laser_distance cells.
+func (g *Grid) Analyze(laser_distance int) *Analysis {
+	a := &Analysis{
+		Width:      g.Width(),
+		Height:     g.Height(),
+		Components: g.Components(nil),
+		Spawns:     g.SpawnScores(laser_distance),
+		DeadEnds:   g.DeadEnds(),
+	}
+	for _, component := range a.Components {
+		a.OpenCells += len(component)
+	}
+	for _, s := range Symmetries {
+		a.Symmetries = append(a.Symmetries, g.CheckSymmetry(s))
+	}
+	a.Fairness = SpawnFairness(a.Spawns)
+	return a
+}
+
+// Analyze checks m over as a map to play on. If m has spawn points, only
+// they are scored as spawns, and it gets a warning if it has fewer of them
+// than the players it says it is for.
+func (m *Map) Analyze(laser_distance int) *Analysis {
+	a := m.Grid.Analyze(laser_distance)
+	if len(m.Spawns) == 0 {
+		return a
+	}
+	spawns := map[Coord]bool{}
+	for _, spawn := range m.Spawns {
+		spawns[spawn.Coord] = true
+	}
+	scores := a.Spawns[:0]
+	for _, score := range a.Spawns {
+		if spawns[score.Coord] {
+			scores = append(scores, score)
+		}
+	}
+	a.Spawns = scores
+	a.Fairness = SpawnFairness(a.Spawns)
+	if m.Players > len(m.Spawns) {
+		a.extra = append(a.extra, fmt.Sprintf(
+			"only %d spawns for %d players", len(m.Spawns), m.Players))
+	}
+	return a
+}
+
+// Connected says whether a tank can get to every open cell from any other.
+func (a *Analysis) Connected() bool {
+	return len(a.Components) <= 1
+}
+
+// ClosestSymmetry returns the symmetry with the fewest mismatches. ok is
+// true if it holds exactly.
+func (a *Analysis) ClosestSymmetry() (check SymmetryCheck, ok bool) {
+	for i, c := range a.Symmetries {
+		if i == 0 || len(c.Mismatches) < len(check.Mismatches) {
+			check = c
+		}
+	}
+	return check, check.Holds()
+}
+
+// Warnings lists what is wrong with the map, for a map to be considered
+// fair if its spawn fairness is at least min_fairness.
+func (a *Analysis) Warnings(min_fairness float64) (warnings []string) {
+	warnings = append(warnings, a.extra...)
+	if !a.Connected() {
+		for _, component := range a.Components[1:] {
+			warnings = append(warnings, fmt.Sprintf(
+				"%d cells starting at %s can't be reached from the rest",
+				len(component), component[0]))
+		}
+	}
+	if check, ok := a.ClosestSymmetry(); !ok {
+		warnings = append(warnings, fmt.Sprintf(
+			"asymmetric: closest to a %s, but %d cells differ: %v",
+			check.Symmetry, len(check.Mismatches), check.Mismatches))
+	}
+	if a.Fairness < min_fairness {
+		warnings = append(warnings, fmt.Sprintf(
+			"unfair spawns: fairness %.2f is below %.2f", a.Fairness,
+			min_fairness))
+	}
+	for _, pocket := range a.DeadEnds {
+		warnings = append(warnings, fmt.Sprintf(
+			"dead end at %s, %d cells deep", pocket[0], len(pocket)))
+	}
+	return warnings
+}
@@ -0,0 +1,126 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package grid
+
+import (
+	"strings"
+	"testing"
+)
+
+// readTestMap reads a map file made of lines.
+func readTestMap(t *testing.T, lines ...string) *Map {
+	m, err := ReadMap(strings.NewReader(strings.Join(lines, "\n") + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestCheckSymmetry(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		lines []string
+		holds map[Symmetry]bool
+	}{
+		{
+			name:  "corners",
+			lines: []string{"W__W", "____", "____", "W__W"},
+			holds: map[Symmetry]bool{MirrorLeftRight: true,
+				MirrorTopBottom: true, Rotate180: true, Rotate90: true},
+		},
+		{
+			// anywhere on a board that wraps around is the middle
+			name:  "off center",
+			lines: []string{"_W___", "_W___", "_____"},
+			holds: map[Symmetry]bool{MirrorLeftRight: true,
+				MirrorTopBottom: true, Rotate180: true},
+		},
+		{
+			name: "off center without the wrap around",
+			lines: []string{"topology: bounded", "_W___", "_W___",
+				"_____"},
+			holds: map[Symmetry]bool{},
+		},
+		{
+			name:  "an L",
+			lines: []string{"WW__", "W___", "____", "____"},
+			holds: map[Symmetry]bool{},
+		},
+	} {
+		g := readTestMap(t, test.lines...).Grid
+		for _, s := range Symmetries {
+			check := g.CheckSymmetry(s)
+			if check.Holds() != test.holds[s] {
+				t.Errorf("%s: expected %s to hold: %v, mismatches: %v",
+					test.name, s, test.holds[s], check.Mismatches)
+			}
+		}
+	}
+}
+
+func TestLinesOfFire(t *testing.T) {
+	g := readTestMap(t, "____", "_W__", "____", "____").Grid
+	for _, test := range []struct {
+		coord                Coord
+		laser_distance, want int
+	}{
+		// the wall blocks one way and cuts the long way around short
+		{Coord{X: 1, Y: 0}, 10, 3 + 3 + 2},
+		{Coord{X: 1, Y: 0}, 2, 2 + 2 + 2},
+		{Coord{X: 0, Y: 0}, 10, 3 * 4},
+		{Coord{X: 1, Y: 2}, 10, 3 + 3 + 2},
+	} {
+		got := g.LinesOfFire(test.coord, test.laser_distance)
+		if got != test.want {
+			t.Errorf("%s with lasers going %d: expected %d, got %d",
+				test.coord, test.laser_distance, test.want, got)
+		}
+	}
+}
+
+func TestSpawnFairness(t *testing.T) {
+	even := []SpawnScore{{WallDistance: 2, LinesOfFire: 5},
+		{WallDistance: 2, LinesOfFire: 5}}
+	if fairness := SpawnFairness(even); fairness != 1 {
+		t.Errorf("expected even spawns to be perfectly fair, got %.2f",
+			fairness)
+	}
+	uneven := []SpawnScore{{WallDistance: 2, LinesOfFire: 0},
+		{WallDistance: 2, LinesOfFire: 10}}
+	if fairness := SpawnFairness(uneven); fairness != 0.5 {
+		t.Errorf("expected uneven spawns to be 0.5 fair, got %.2f", fairness)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	m := readTestMap(t,
+		"players: 4",
+		"WWWWWW",
+		"W^W__W",
+		"W_WWvW",
+		"WWWWWW")
+	a := m.Analyze(10)
+	if a.Connected() || len(a.Components) != 2 || a.OpenCells != 5 {
+		t.Errorf("expected 2 components with 5 cells, got %v",
+			a.Components)
+	}
+	if len(a.Spawns) != 2 {
+		t.Errorf("expected only the 2 spawns to be scored, got %d",
+			len(a.Spawns))
+	}
+	if len(a.DeadEnds) == 0 {
+		t.Error("expected dead ends")
+	}
+
+	warnings := strings.Join(a.Warnings(0), "\n")
+	for _, want := range []string{
+		"only 2 spawns for 4 players",
+		"can't be reached from the rest",
+		"asymmetric",
+		"dead end at",
+	} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("expected a warning about %q in:\n%s", want, warnings)
+		}
+	}
+}
@@ -0,0 +1,369 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package grid
+
+import (
+	"math/rand"
+	"strings"
+)
+
+// Algorithm is a way of laying out walls.
+type Algorithm string
+
+const (
+	// Segments drops random horizontal and vertical wall segments, like
+	// NewRandom.
+	Segments Algorithm = "segments"
+	// Rooms carves rectangular rooms out of solid wall and joins them up
+	// with corridors.
+	Rooms Algorithm = "rooms"
+	// Caves fills the grid with random walls and smooths them out into
+	// caverns.
+	Caves Algorithm = "caves"
+)
+
+var Algorithms = []Algorithm{Segments, Rooms, Caves}
+
+var symmetryNames = map[string][]Symmetry{
+	"none":         nil,
+	"left-right":   {MirrorLeftRight},
+	"top-bottom":   {MirrorTopBottom},
+	"half-turn":    {Rotate180},
+	"quarter-turn": {Rotate90},
+	"four-way":     {MirrorLeftRight, MirrorTopBottom},
+}
+
+// ParseSymmetry turns one of none, left-right, top-bottom, half-turn,
+// quarter-turn or four-way into the symmetries it stands for.
+func ParseSymmetry(name string) ([]Symmetry, error) {
+	symmetries, ok := symmetryNames[strings.ToLower(name)]
+	if !ok {
+		return nil, GridError.New("unknown symmetry %q", name)
+	}
+	return symmetries, nil
+}
+
+// Options says what kind of grid Generate should make.
+type Options struct {
+	Width  int
+	Height int
+	// Algorithm defaults to Segments.
+	Algorithm Algorithm
+	// Density is roughly what fraction of the cells should be walls, not
+	// counting the border of an enclosed grid.
+	Density float64
+	// Enclosed puts a wall all the way around the grid.
+	Enclosed bool
+	// Bounded makes a grid that doesn't wrap around.
+	Bounded bool
+	// Symmetries are the ways the grid should look the same, so that no
+	// player's half (or quarter) of it is any better than another's. If
+	// Symmetries is nil, Players picks them: a half turn for two players,
+	// and a quarter turn (or both mirrors, if the grid isn't square) for
+	// four.
+	Symmetries []Symmetry
+	Players    int
+	// Seed makes Generate come up with the same grid every time.
+	Seed int64
+}
+
+func (o Options) symmetries() []Symmetry {
+	if o.Symmetries != nil {
+		return o.Symmetries
+	}
+	switch {
+	case o.Players == 2:
+		return []Symmetry{Rotate180}
+	case o.Players >= 4 && o.Width == o.Height:
+		return []Symmetry{Rotate90}
+	case o.Players >= 4:
+		return []Symmetry{MirrorLeftRight, MirrorTopBottom}
+	}
+	return nil
+}
+
+// Generate makes a new grid as described by opts. Every open cell of the
+// result can be reached from every other, walls are knocked out where
+// needed to make sure of it.
+func Generate(opts Options) (*Grid, error) {
+	if opts.Width < 3 || opts.Height < 3 {
+		return nil, GridError.New("grid of %dx%d is too small", opts.Width,
+			opts.Height)
+	}
+	if opts.Density < 0 || opts.Density >= 1 {
+		return nil, GridError.New("density %v is not between 0 and 1",
+			opts.Density)
+	}
+	symmetries := opts.symmetries()
+	for _, s := range symmetries {
+		if s == Rotate90 && opts.Width != opts.Height {
+			return nil, GridError.New(
+				"a quarter turn needs a square grid, not %dx%d", opts.Width,
+				opts.Height)
+		}
+	}
+
+	r := rand.New(rand.NewSource(opts.Seed))
+	g := NewEmpty(opts.Width, opts.Height)
+	g.SetBounded(opts.Bounded)
+	switch opts.Algorithm {
+	case Segments, "":
+		g.generateSegments(r, opts.Density)
+	case Rooms:
+		g.generateRooms(r, opts.Density)
+	case Caves:
+		g.generateCaves(r, opts.Density)
+	default:
+		return nil, GridError.New("unknown algorithm %q", opts.Algorithm)
+	}
+
+	if opts.Enclosed {
+		for x := 0; x < g.Width(); x++ {
+			g.cells[0][x] = WallCell
+			g.cells[g.Height()-1][x] = WallCell
+		}
+		for y := 0; y < g.Height(); y++ {
+			g.cells[y][0] = WallCell
+			g.cells[y][g.Width()-1] = WallCell
+		}
+	}
+	g.symmetrize(symmetries)
+	g.connect(symmetries, opts.Enclosed)
+	if len(g.Components(nil)) == 0 {
+		return nil, GridError.New("generated a grid with no open cells")
+	}
+	return g, nil
+}
+
+// orbit returns coord along with every cell symmetries take it to. The
+// mirror lines and centers of rotation are always in the middle of the grid.
+func (g *Grid) orbit(coord Coord, symmetries []Symmetry) []Coord {
+	shifts := g.centers()
+	orbit := []Coord{coord}
+	seen := map[Coord]bool{coord: true}
+	for i := 0; i < len(orbit); i++ {
+		for _, s := range symmetries {
+			next := g.counterpart(s, shifts[s], orbit[i])
+			if !seen[next] {
+				seen[next] = true
+				orbit = append(orbit, next)
+			}
+		}
+	}
+	return orbit
+}
+
+// symmetrize makes every cell look like the first cell, in reading order,
+// of its orbit.
+func (g *Grid) symmetrize(symmetries []Symmetry) {
+	if len(symmetries) == 0 {
+		return
+	}
+	for y := 0; y < g.Height(); y++ {
+		for x := 0; x < g.Width(); x++ {
+			first := Coord{X: x, Y: y}
+			for _, coord := range g.orbit(first, symmetries) {
+				if coord.Y < first.Y || (coord.Y == first.Y && coord.X < first.X) {
+					first = coord
+				}
+			}
+			g.cells[y][x] = g.cells[first.Y][first.X]
+		}
+	}
+}
+
+// connect knocks out walls until every open cell can be reached from every
+// other, along with the walls symmetric to them so the symmetry holds. The
+// border of an enclosed grid is left alone.
+func (g *Grid) connect(symmetries []Symmetry, enclosed bool) {
+	border := func(coord Coord) bool {
+		return enclosed && (coord.X == 0 || coord.Y == 0 ||
+			coord.X == g.Width()-1 || coord.Y == g.Height()-1)
+	}
+	for {
+		components := g.Components(nil)
+		if len(components) <= 1 {
+			return
+		}
+		// tunnel from the smallest area to the closest cell of any other
+		smallest := components[len(components)-1]
+		inside := map[Coord]bool{}
+		for _, coord := range smallest {
+			inside[coord] = true
+		}
+		prev := map[Coord]Coord{}
+		queue := append([]Coord(nil), smallest...)
+		var end *Coord
+		for len(queue) > 0 && end == nil {
+			coord := queue[0]
+			queue = queue[1:]
+			for _, next := range g.Neighbors(coord) {
+				if _, ok := prev[next]; ok || inside[next] || border(next) {
+					continue
+				}
+				prev[next] = coord
+				if g.Passable(next) {
+					end = &next
+					break
+				}
+				queue = append(queue, next)
+			}
+		}
+		if end == nil {
+			return
+		}
+		for coord := prev[*end]; !inside[coord]; coord = prev[coord] {
+			for _, c := range g.orbit(coord, symmetries) {
+				g.cells[c.Y][c.X] = EmptyCell
+			}
+		}
+	}
+}
+
+func (g *Grid) wallCount() (count int) {
+	for _, row := range g.cells {
+		for _, cell := range row {
+			if cell.Type == Wall {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func (g *Grid) generateSegments(r *rand.Rand, density float64) {
+	width, height := g.Width(), g.Height()
+	target := int(density * float64(width*height))
+	for tries := 0; g.wallCount() < target && tries < width*height; tries++ {
+		if r.Intn(2) == 0 {
+			length := 1 + r.Intn(width/2)
+			start, row := r.Intn(width), r.Intn(height)
+			for i := 0; i < length; i++ {
+				g.cells[row][(start+i)%width] = WallCell
+			}
+		} else {
+			length := 1 + r.Intn(height/2)
+			start, col := r.Intn(height), r.Intn(width)
+			for i := 0; i < length; i++ {
+				g.cells[(start+i)%height][col] = WallCell
+			}
+		}
+	}
+}
+
+func (g *Grid) generateRooms(r *rand.Rand, density float64) {
+	width, height := g.Width(), g.Height()
+	for y := range g.cells {
+		for x := range g.cells[y] {
+			g.cells[y][x] = WallCell
+		}
+	}
+	target := int(density * float64(width*height))
+	var centers []Coord
+	for tries := 0; g.wallCount() > target && tries < width*height; tries++ {
+		w := 2 + r.Intn(max(1, width/4))
+		h := 2 + r.Intn(max(1, height/4))
+		x0, y0 := r.Intn(width), r.Intn(height)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				g.cells[(y0+y)%height][(x0+x)%width] = EmptyCell
+			}
+		}
+		center := Coord{X: (x0 + w/2) % width, Y: (y0 + h/2) % height}
+		if len(centers) > 0 {
+			g.carveCorridor(centers[r.Intn(len(centers))], center)
+		}
+		centers = append(centers, center)
+	}
+}
+
+// carveCorridor digs an L shaped corridor from a to b, going the short way
+// around the board.
+func (g *Grid) carveCorridor(a, b Coord) {
+	dx, dy := g.Offset(a, b)
+	coord := a
+	g.cells[coord.Y][coord.X] = EmptyCell
+	for ; dx != 0; dx -= sign(dx) {
+		coord.X = mod(coord.X+sign(dx), g.Width())
+		g.cells[coord.Y][coord.X] = EmptyCell
+	}
+	for ; dy != 0; dy -= sign(dy) {
+		coord.Y = mod(coord.Y+sign(dy), g.Height())
+		g.cells[coord.Y][coord.X] = EmptyCell
+	}
+}
+
+func (g *Grid) generateCaves(r *rand.Rand, density float64) {
+	// smoothing wears away scattered walls and fills in crowded ones, so
+	// try a range of starting fills and keep whichever comes out closest
+	target := int(density * float64(g.Width()*g.Height()))
+	var best [][]Cell
+	best_off := -1
+	for fill := 0.3; fill < 0.6; fill += 0.02 {
+		g.fillCaves(r, fill)
+		off := g.wallCount() - target
+		if off < 0 {
+			off = -off
+		}
+		if best_off < 0 || off < best_off {
+			best, best_off = g.cells, off
+		}
+	}
+	g.cells = best
+}
+
+func (g *Grid) fillCaves(r *rand.Rand, fill float64) {
+	width, height := g.Width(), g.Height()
+	g.cells = newCells(width, height)
+	for y := range g.cells {
+		for x := range g.cells[y] {
+			if r.Float64() < fill {
+				g.cells[y][x] = WallCell
+			}
+		}
+	}
+	// smooth things out: cells with mostly walls around them become walls,
+	// the rest open up
+	for step := 0; step < 4; step++ {
+		next := newCells(width, height)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				walls := 0
+				for ny := y - 1; ny <= y+1; ny++ {
+					for nx := x - 1; nx <= x+1; nx++ {
+						if (nx != x || ny != y) &&
+							g.cells[mod(ny, height)][mod(nx, width)].Type == Wall {
+							walls++
+						}
+					}
+				}
+				switch {
+				case walls >= 5:
+					next[y][x] = WallCell
+				case walls <= 3:
+					next[y][x] = EmptyCell
+				default:
+					next[y][x] = g.cells[y][x]
+				}
+			}
+		}
+		g.cells = next
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,117 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package grid
+
+import (
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	for _, algorithm := range Algorithms {
+		for _, name := range []string{"none", "left-right", "top-bottom",
+			"half-turn", "quarter-turn", "four-way"} {
+			symmetries, err := ParseSymmetry(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for seed := int64(0); seed < 5; seed++ {
+				opts := Options{
+					Width:      16,
+					Height:     16,
+					Algorithm:  algorithm,
+					Density:    0.3,
+					Enclosed:   seed%2 == 0,
+					Bounded:    seed%3 == 0,
+					Symmetries: symmetries,
+					Seed:       seed,
+				}
+				g, err := Generate(opts)
+				if err != nil {
+					t.Fatalf("%s, %s, seed %d: %v", algorithm, name, seed, err)
+				}
+				checkGenerated(t, g, opts)
+			}
+		}
+	}
+}
+
+func checkGenerated(t *testing.T, g *Grid, opts Options) {
+	if g.Width() != opts.Width || g.Height() != opts.Height ||
+		g.Bounded() != opts.Bounded {
+		t.Errorf("%s, seed %d: expected a %dx%d grid, bounded: %v",
+			opts.Algorithm, opts.Seed, opts.Width, opts.Height, opts.Bounded)
+	}
+	if components := g.Components(nil); len(components) != 1 {
+		t.Errorf("%s, seed %d: expected everything to be connected, got %d "+
+			"components", opts.Algorithm, opts.Seed, len(components))
+	}
+	for _, s := range opts.Symmetries {
+		if check := g.CheckSymmetry(s); !check.Holds() {
+			t.Errorf("%s, seed %d: expected a %s, but %v differ",
+				opts.Algorithm, opts.Seed, s, check.Mismatches)
+		}
+	}
+	if opts.Enclosed {
+		for x := 0; x < g.Width(); x++ {
+			for _, y := range []int{0, g.Height() - 1} {
+				if g.CellAt(Coord{X: x, Y: y}).Type != Wall {
+					t.Errorf("%s, seed %d: expected a wall at (%d,%d)",
+						opts.Algorithm, opts.Seed, x, y)
+				}
+			}
+		}
+	}
+
+	again, err := Generate(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.SerializeFor(0) != g.SerializeFor(0) {
+		t.Errorf("%s, seed %d: the same seed made a different grid",
+			opts.Algorithm, opts.Seed)
+	}
+}
+
+func TestGenerateSymmetriesByPlayers(t *testing.T) {
+	for _, test := range []struct {
+		width, height, players int
+		want                   []Symmetry
+	}{
+		{16, 16, 2, []Symmetry{Rotate180}},
+		{16, 16, 4, []Symmetry{Rotate90}},
+		{20, 16, 4, []Symmetry{MirrorLeftRight, MirrorTopBottom}},
+		{16, 16, 3, nil},
+	} {
+		opts := Options{Width: test.width, Height: test.height,
+			Players: test.players}
+		got := opts.symmetries()
+		if len(got) != len(test.want) {
+			t.Errorf("%d players on %dx%d: expected %v, got %v", test.players,
+				test.width, test.height, test.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%d players on %dx%d: expected %v, got %v",
+					test.players, test.width, test.height, test.want, got)
+			}
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for _, opts := range []Options{
+		{Width: 2, Height: 10},
+		{Width: 10, Height: 10, Density: 1},
+		{Width: 10, Height: 10, Density: -0.1},
+		{Width: 10, Height: 12, Symmetries: []Symmetry{Rotate90}},
+		{Width: 10, Height: 10, Algorithm: "mazes"},
+	} {
+		if _, err := Generate(opts); err == nil {
+			t.Errorf("%+v: expected an error", opts)
+		}
+	}
+	if _, err := ParseSymmetry("sideways"); err == nil {
+		t.Error("expected an error for an unknown symmetry")
+	}
+}
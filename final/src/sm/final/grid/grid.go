@@ -3,12 +3,10 @@
 package grid
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
 	"math/rand"
-	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -28,6 +26,30 @@ const (
 	Player  Type = "player"
 	Battery Type = "battery"
 	Laser   Type = "laser"
+	// Portal cells come in pairs. Anything going into one comes out of the
+	// other, facing the same way.
+	Portal Type = "portal"
+	// Brick cells are walls that lasers wear down and eventually knock out.
+	Brick Type = "brick"
+	// Mirror cells are diagonal mirrors that lasers bounce off of and tanks
+	// can't get past. Their orientation says which way they lean; see
+	// SlashMirror and BackslashMirror.
+	Mirror Type = "mirror"
+	// Charge cells are charged shots, lasers that hit harder and go further.
+	Charge Type = "charge"
+	Mine   Type = "mine"
+	// EnergyCell, RepairKit, DoubleDamage and Speed cells are pickups that
+	// only give energy, only give health, or give a power-up for a while.
+	EnergyCell   Type = "energy-cell"
+	RepairKit    Type = "repair-kit"
+	DoubleDamage Type = "double-damage"
+	Speed        Type = "speed"
+	// Lava, Ice and Slow cells are hazards: tanks can drive onto them, but
+	// lava burns tanks sitting on it, tanks driving onto ice slide an extra
+	// cell, and tanks take an extra turn to drive off of slow cells.
+	Lava Type = "lava"
+	Ice  Type = "ice"
+	Slow Type = "slow"
 )
 
 func (t Type) MarshalJSON() ([]byte, error) {
@@ -51,6 +73,30 @@ func (t *Type) UnmarshalJSON(p []byte) (err error) {
 		*t = Battery
 	case Laser:
 		*t = Laser
+	case Portal:
+		*t = Portal
+	case Brick:
+		*t = Brick
+	case Mirror:
+		*t = Mirror
+	case Charge:
+		*t = Charge
+	case Mine:
+		*t = Mine
+	case EnergyCell:
+		*t = EnergyCell
+	case RepairKit:
+		*t = RepairKit
+	case DoubleDamage:
+		*t = DoubleDamage
+	case Speed:
+		*t = Speed
+	case Lava:
+		*t = Lava
+	case Ice:
+		*t = Ice
+	case Slow:
+		*t = Slow
 	default:
 		return errors.New(fmt.Sprintf("%s is not a valid cell type", raw))
 	}
@@ -189,12 +235,22 @@ type Owner int
 
 const (
 	None Owner = 0
+	// Other is the owner of tanks that belong to someone else in a grid parsed
+	// with Deserialize, since serialized grids don't say who they belong to.
+	Other Owner = -1
+	// Ally is the owner of tanks on the same team in a grid parsed with
+	// Deserialize.
+	Ally Owner = -2
 )
 
 func (o Owner) String() string {
 	switch o {
 	case None:
 		return "None"
+	case Other:
+		return "Other"
+	case Ally:
+		return "Ally"
 	default:
 		return fmt.Sprintf("Player%d", o)
 	}
@@ -206,11 +262,65 @@ type Cell struct {
 	Orientation
 	Owner
 	Exploding bool
+	// Pair is which pair a Portal cell belongs to, from 0 to 9.
+	Pair int
+	// Damage is how many times a Brick cell has been hit.
+	Damage int
+	// Shielded says whether a Player cell has its shield up.
+	Shielded bool
+}
+
+// Solid says whether tanks and lasers stop at c, like they do at walls and
+// bricks.
+func (c Cell) Solid() bool {
+	return c.Type == Wall || c.Type == Brick
+}
+
+// Blocks says whether tanks can't drive onto c. On top of everything solid,
+// that includes mirrors, which lasers bounce off of instead.
+func (c Cell) Blocks() bool {
+	return c.Solid() || c.Type == Mirror
+}
+
+// Pickup says whether c is something tanks pick up by driving onto it, like
+// a battery.
+func (c Cell) Pickup() bool {
+	switch c.Type {
+	case Battery, EnergyCell, RepairKit, DoubleDamage, Speed:
+		return true
+	}
+	return false
+}
+
+// Hazard says whether c is terrain that tanks can drive onto but that does
+// something to them, like lava.
+func (c Cell) Hazard() bool {
+	return c.Type == Lava || c.Type == Ice || c.Type == Slow
+}
+
+// Reflect returns which way something heading towards o is heading after it
+// bounces off the mirror c.
+func (c Cell) Reflect(o Orientation) Orientation {
+	slash := c.Orientation == North || c.Orientation == South
+	switch {
+	case slash && (o == North || o == South):
+		return o.Right()
+	case slash:
+		return o.Left()
+	case o == North || o == South:
+		return o.Left()
+	default:
+		return o.Right()
+	}
 }
 
 var (
 	EmptyCell = Cell{Type: Empty}
 	WallCell  = Cell{Type: Wall}
+	// SlashMirror leans like /, so lasers heading north bounce east
+	SlashMirror = Cell{Type: Mirror, Orientation: North}
+	// BackslashMirror leans like \, so lasers heading north bounce west
+	BackslashMirror = Cell{Type: Mirror, Orientation: East}
 )
 
 type Coord struct {
@@ -224,6 +334,12 @@ func (c Coord) String() string {
 
 type Grid struct {
 	cells [][]Cell
+	// bounded grids don't wrap around; their edges stop tanks and lasers
+	// like walls do
+	bounded bool
+	// partners maps each portal to the other one of its pair. Portals stay
+	// where the map put them, so it is shared between copies.
+	partners map[Coord]Coord
 }
 
 func NewEmpty(width, height int) *Grid {
@@ -245,7 +361,14 @@ func newCells(width, height int) (rv [][]Cell) {
 }
 
 func NewRandom(width, height int, walls int, enclosed bool) *Grid {
-	r := rand.New(rand.NewSource(time.Now().Unix()))
+	return NewSeededRandom(width, height, walls, enclosed, time.Now().Unix())
+}
+
+// NewSeededRandom is like NewRandom, but always lays out the same walls for
+// the same seed.
+func NewSeededRandom(width, height int, walls int, enclosed bool,
+	seed int64) *Grid {
+	r := rand.New(rand.NewSource(seed))
 
 	grid := NewEmpty(width, height)
 	rv := grid.cells
@@ -296,6 +419,25 @@ func (g *Grid) CopyTo(other *Grid) {
 	}
 
 	other.cells = dest
+	other.bounded = g.bounded
+	other.partners = g.partners
+}
+
+// Clone returns a copy of g that shares nothing with it.
+func (g *Grid) Clone() *Grid {
+	clone := &Grid{}
+	g.CopyTo(clone)
+	return clone
+}
+
+// SetBounded says whether the grid wraps around (false, the default) or
+// stops at its edges (true).
+func (g *Grid) SetBounded(bounded bool) {
+	g.bounded = bounded
+}
+
+func (g *Grid) Bounded() bool {
+	return g.bounded
 }
 
 func (g *Grid) Width() int {
@@ -348,12 +490,46 @@ func (g *Grid) cellAt(coord Coord) *Cell {
 	return &g.cells[coord.Y][coord.X]
 }
 
+// RelativeTo returns the coordinate next to coord in the given orientation.
+// Going off the edge of a bounded grid leaves coord where it is.
 func (g *Grid) RelativeTo(coord Coord, orientation Orientation) (
 	rv Coord) {
+	rv, _ = g.Step(coord, orientation)
+	return rv
+}
+
+// Step is like RelativeTo, but ok is false if it would go off the edge of a
+// bounded grid. Stepping into a portal comes out one cell past the other
+// portal of its pair.
+func (g *Grid) Step(coord Coord, orientation Orientation) (rv Coord,
+	ok bool) {
+	rv, ok = g.step(coord, orientation)
+	if !ok {
+		return coord, false
+	}
+	if cell := g.cellAt(rv); cell != nil && cell.Type == Portal {
+		partner, found := g.Partner(rv)
+		if !found {
+			return rv, true
+		}
+		rv, ok = g.step(partner, orientation)
+		if !ok {
+			return coord, false
+		}
+	}
+	return rv, true
+}
+
+func (g *Grid) step(coord Coord, orientation Orientation) (rv Coord,
+	ok bool) {
 	rv = coord
 	dx, dy := orientation.Delta()
 	rv.X += dx
 	rv.Y += dy
+	if g.bounded && (rv.X < 0 || rv.Y < 0 || rv.X >= g.Width() ||
+		rv.Y >= g.Height()) {
+		return coord, false
+	}
 	if rv.Y < 0 {
 		rv.Y = g.Height() - 1
 	}
@@ -366,44 +542,199 @@ func (g *Grid) RelativeTo(coord Coord, orientation Orientation) (
 	if rv.X >= g.Width() {
 		rv.X = 0
 	}
+	return rv, true
+}
+
+// Partner returns where the other portal of the pair the portal at coord
+// belongs to is. Only portals the grid was read or deserialized with have
+// partners.
+func (g *Grid) Partner(coord Coord) (Coord, bool) {
+	partner, ok := g.partners[coord]
+	return partner, ok
+}
+
+// Offset returns the shortest displacement from a to b, taking into account
+// that the board wraps around unless it is bounded.
+func (g *Grid) Offset(a, b Coord) (dx, dy int) {
+	if g.bounded {
+		return b.X - a.X, b.Y - a.Y
+	}
+	return wrapDelta(b.X-a.X, g.Width()), wrapDelta(b.Y-a.Y, g.Height())
+}
+
+func wrapDelta(delta, size int) int {
+	if size == 0 {
+		return 0
+	}
+	delta %= size
+	switch {
+	case delta > size/2:
+		delta -= size
+	case delta < -size/2:
+		delta += size
+	}
+	return delta
+}
+
+// Distance returns how many moves it takes to get from a to b, ignoring
+// walls and turns, taking into account that the board wraps around.
+func (g *Grid) Distance(a, b Coord) int {
+	dx, dy := g.Offset(a, b)
+	return abs(dx) + abs(dy)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Neighbors returns the coordinates next to coord, in orientation order
+// starting from North. On a bounded grid, cells on the edge have fewer of
+// them.
+func (g *Grid) Neighbors(coord Coord) []Coord {
+	rv := make([]Coord, 0, 4)
+	for o := North; o <= West; o++ {
+		if next, ok := g.Step(coord, o); ok {
+			rv = append(rv, next)
+		}
+	}
 	return rv
 }
 
+// CellRelativeTo returns the cell next to coord in the given orientation,
+// along with where it is, going through portals like Step does. Off the edge
+// of a bounded grid there is a wall, at coord itself, and a portal that comes
+// out right into another portal acts like a wall too.
 func (g *Grid) CellRelativeTo(coord Coord, orientation Orientation) (Cell,
 	Coord) {
-	new_coord := g.RelativeTo(coord, orientation)
+	new_coord, ok := g.Step(coord, orientation)
+	if !ok {
+		return WallCell, coord
+	}
 	cell := g.cellAt(new_coord)
 	if cell == nil {
 		panic(fmt.Sprintf("expected cell at %s; got nil", coord))
 	}
+	if cell.Type == Portal {
+		return WallCell, new_coord
+	}
 	return *cell, new_coord
 }
 
+// LaserStep returns where a laser on coord heading towards orientation goes
+// next, which way it is heading once it gets there, and the cell it runs
+// into there. Lasers go through portals like tanks do and bounce off
+// mirrors, never stopping on either. ok is false if the laser goes off the
+// edge of a bounded grid.
+func (g *Grid) LaserStep(coord Coord, orientation Orientation) (cell Cell,
+	next Coord, heading Orientation, ok bool) {
+	next = coord
+	for bounces := 0; bounces <= g.Width()*g.Height(); bounces++ {
+		if _, ok = g.Step(next, orientation); !ok {
+			return WallCell, next, orientation, false
+		}
+		cell, next = g.CellRelativeTo(next, orientation)
+		if cell.Type != Mirror {
+			return cell, next, orientation, true
+		}
+		orientation = cell.Reflect(orientation)
+	}
+	// stuck bouncing around between mirrors forever
+	return WallCell, next, orientation, true
+}
+
+// LoadFromFile reads the grid out of the map file at path. See LoadMap for
+// everything else a map file can say.
 func LoadFromFile(path string) (*Grid, error) {
-	file, err := os.Open(path)
+	m, err := LoadMap(path)
 	if err != nil {
-		return nil, GridError.Wrap(err)
+		return nil, err
 	}
-	defer file.Close()
+	return m.Grid, nil
+}
 
-	var cells [][]Cell
-	var width int
+// SerializeFor returns g as owner sees it, with owner's tank as X, the tanks
+// of any allies as A and everybody else's as O.
+func (g *Grid) SerializeFor(owner Owner, allies ...Owner) string {
+	is_ally := map[Owner]bool{}
+	for _, ally := range allies {
+		is_ally[ally] = true
+	}
+	var buf bytes.Buffer
+	for y := 0; y < len(g.cells); y++ {
+		for x := 0; x < len(g.cells[y]); x++ {
+			cell := g.cells[y][x]
 
-	scanner := bufio.NewScanner(file)
-	lineno := 0
-	for ; scanner.Scan(); lineno++ {
-		line := scanner.Text()
+			r := '_'
+			switch cell.Type {
+			case Wall:
+				r = 'W'
+			case Player:
+				switch {
+				case cell.Owner == owner:
+					r = 'X'
+				case is_ally[cell.Owner]:
+					r = 'A'
+				default:
+					r = 'O'
+				}
+			case Battery:
+				r = 'B'
+			case EnergyCell:
+				r = 'E'
+			case RepairKit:
+				r = 'R'
+			case DoubleDamage:
+				r = 'P'
+			case Speed:
+				r = 'S'
+			case Lava, Ice, Slow:
+				r = hazardGlyphs[cell.Type]
+			case Laser:
+				r = 'L'
+			case Portal:
+				r = rune('0' + cell.Pair)
+			case Brick:
+				if cell.Damage > 0 {
+					r = 'd'
+				} else {
+					r = 'D'
+				}
+			case Mirror:
+				r = mirrorGlyph(cell)
+			case Charge:
+				r = 'C'
+			case Mine:
+				if cell.Owner == owner {
+					r = 'M'
+				} else {
+					r = 'm'
+				}
+			}
+			buf.WriteRune(r)
+		}
+		buf.WriteRune('\n')
+	}
+	return buf.String()
+}
+
+// Deserialize parses a grid serialized by SerializeFor(owner). Since the
+// serialized form doesn't say who other tanks belong to or which way anything
+// is facing, other tanks and mines are owned by Other (or Ally, for tanks on
+// the same team) and everything faces North.
+// Likewise, it only says whether bricks have been hit at all, so damaged
+// bricks come back with a Damage of 1.
+func Deserialize(serialized string, owner Owner) (*Grid, error) {
+	var cells [][]Cell
+	for lineno, line := range strings.Split(serialized, "\n") {
 		if line == "" {
 			continue
 		}
-		if width > 0 {
-			if len(line) != width {
-				return nil, GridError.New(
-					"expected width %d on line %d, got %d",
-					width, lineno, len(line))
-			}
-		} else {
-			width = len(line)
+		if len(cells) > 0 && len(line) != len(cells[0]) {
+			return nil, GridError.New("expected width %d on line %d, got %d",
+				len(cells[0]), lineno, len(line))
 		}
 		row := make([]Cell, 0, len(line))
 		for _, c := range line {
@@ -412,48 +743,97 @@ func LoadFromFile(path string) (*Grid, error) {
 				row = append(row, EmptyCell)
 			case 'W':
 				row = append(row, WallCell)
+			case 'X':
+				row = append(row, Cell{Type: Player, Owner: owner})
+			case 'O':
+				row = append(row, Cell{Type: Player, Owner: Other})
+			case 'A':
+				row = append(row, Cell{Type: Player, Owner: Ally})
+			case 'B':
+				row = append(row, Cell{Type: Battery})
+			case 'E':
+				row = append(row, Cell{Type: EnergyCell})
+			case 'R':
+				row = append(row, Cell{Type: RepairKit})
+			case 'P':
+				row = append(row, Cell{Type: DoubleDamage})
+			case 'S':
+				row = append(row, Cell{Type: Speed})
+			case '~':
+				row = append(row, Cell{Type: Lava})
+			case '*':
+				row = append(row, Cell{Type: Ice})
+			case '%':
+				row = append(row, Cell{Type: Slow})
+			case 'L':
+				row = append(row, Cell{Type: Laser})
+			case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+				row = append(row, Cell{Type: Portal, Pair: int(c - '0')})
+			case 'D':
+				row = append(row, Cell{Type: Brick})
+			case 'd':
+				row = append(row, Cell{Type: Brick, Damage: 1})
+			case '/':
+				row = append(row, SlashMirror)
+			case '\\':
+				row = append(row, BackslashMirror)
+			case 'C':
+				row = append(row, Cell{Type: Charge})
+			case 'M':
+				row = append(row, Cell{Type: Mine, Owner: owner})
+			case 'm':
+				row = append(row, Cell{Type: Mine, Owner: Other})
 			default:
-				return nil, GridError.New("unexpected character %v on line %d",
+				return nil, GridError.New("unexpected character %q on line %d",
 					c, lineno)
 			}
 		}
 		cells = append(cells, row)
 	}
-	if err = scanner.Err(); err != nil {
-		return nil, GridError.Wrap(err)
-	}
 	if len(cells) == 0 {
-		return nil, GridError.New("empty grid file")
+		return nil, GridError.New("empty grid")
+	}
+	partners, err := findPartners(cells)
+	if err != nil {
+		return nil, err
 	}
 	return &Grid{
-		cells: cells,
+		cells:    cells,
+		partners: partners,
 	}, nil
 }
 
-func (g *Grid) SerializeFor(owner Owner) string {
-	var buf bytes.Buffer
-	for y := 0; y < len(g.cells); y++ {
-		for x := 0; x < len(g.cells[y]); x++ {
-			cell := g.cells[y][x]
-
-			r := '_'
-			switch cell.Type {
-			case Wall:
-				r = 'W'
-			case Player:
-				if cell.Owner == owner {
-					r = 'X'
-				} else {
-					r = 'O'
-				}
-			case Battery:
-				r = 'B'
-			case Laser:
-				r = 'L'
+// findPartners pairs up the portals in cells, making sure every portal has
+// exactly one partner.
+func findPartners(cells [][]Cell) (partners map[Coord]Coord, err error) {
+	pairs := map[int][]Coord{}
+	for y, row := range cells {
+		for x, cell := range row {
+			if cell.Type == Portal {
+				pairs[cell.Pair] = append(pairs[cell.Pair], Coord{X: x, Y: y})
 			}
-			buf.WriteRune(r)
 		}
-		buf.WriteRune('\n')
 	}
-	return buf.String()
+	partners = map[Coord]Coord{}
+	for pair := 0; pair <= 9; pair++ {
+		coords := pairs[pair]
+		switch len(coords) {
+		case 0:
+		case 2:
+			partners[coords[0]] = coords[1]
+			partners[coords[1]] = coords[0]
+		default:
+			return nil, GridError.New(
+				"portal %d shows up %d times instead of twice", pair,
+				len(coords))
+		}
+	}
+	return partners, nil
+}
+
+func mirrorGlyph(cell Cell) rune {
+	if cell.Reflect(North) == East {
+		return '/'
+	}
+	return '\\'
 }
@@ -0,0 +1,279 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package grid
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Spawn is where a player starts out, and which way it faces.
+type Spawn struct {
+	Coord       Coord
+	Orientation Orientation
+}
+
+// Map is a grid along with everything else a map file says about it.
+//
+// A map file starts with an optional header of "key: value" lines, with the
+// keys name, author, players (how many players the map is meant for), preset
+// (the name of the game rules it is meant to be played with) and topology
+// (wrap, the default, or bounded for a grid that doesn't wrap around). Lines
+// starting with # are comments. The grid follows, one row per line:
+//
+//	_        empty
+//	W        wall
+//	D        brick, a wall lasers can knock out
+//	/ \      mirror leaning that way, which lasers bounce off of at a right
+//	         angle and tanks can't get past
+//	^ > v <  empty, and where a player starts out facing that way
+//	b        empty, and part of a zone batteries spawn in
+//	B        empty, and a fixed battery spawner
+//	0-9      portal; each digit has to show up exactly twice, and anything
+//	         going into one of the pair comes out of the other
+//	~        lava, which burns tanks sitting on it
+//	*        ice, which tanks driving onto slide an extra cell across
+//	%        slow ground, which takes an extra turn to drive off of
+//
+// Players are handed spawns in reading order, left to right and then top to
+// bottom. Maps with no header and only _ and W, like all of the original
+// ones, still load the same as ever.
+type Map struct {
+	Name    string
+	Author  string
+	Players int
+	Preset  string
+
+	Grid            *Grid
+	Spawns          []Spawn
+	BatteryZones    []Coord
+	BatterySpawners []Coord
+
+	bounded bool
+}
+
+var spawnGlyphs = map[rune]Orientation{
+	'^': North,
+	'>': East,
+	'v': South,
+	'<': West,
+}
+
+// hazardGlyphs are how hazards show up in map files and serialized grids.
+var hazardGlyphs = map[Type]rune{
+	Lava: '~',
+	Ice:  '*',
+	Slow: '%',
+}
+
+// LoadMap reads the map file at path.
+func LoadMap(path string) (*Map, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, GridError.Wrap(err)
+	}
+	defer file.Close()
+	return ReadMap(file)
+}
+
+// ReadMap reads a map file from r.
+func ReadMap(r io.Reader) (*Map, error) {
+	m := &Map{}
+	var cells [][]Cell
+	var width int
+
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+	for ; scanner.Scan(); lineno++ {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if len(cells) == 0 && strings.Contains(line, ":") {
+			err := m.setHeader(line, lineno)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if width > 0 {
+			if len(line) != width {
+				return nil, GridError.New(
+					"expected width %d on line %d, got %d",
+					width, lineno, len(line))
+			}
+		} else {
+			width = len(line)
+		}
+		y := len(cells)
+		row := make([]Cell, 0, len(line))
+		for x, c := range line {
+			coord := Coord{X: x, Y: y}
+			switch c {
+			case '_':
+				row = append(row, EmptyCell)
+			case 'W':
+				row = append(row, WallCell)
+			case 'D':
+				row = append(row, Cell{Type: Brick})
+			case '/':
+				row = append(row, SlashMirror)
+			case '\\':
+				row = append(row, BackslashMirror)
+			case '^', '>', 'v', '<':
+				row = append(row, EmptyCell)
+				m.Spawns = append(m.Spawns, Spawn{Coord: coord,
+					Orientation: spawnGlyphs[c]})
+			case 'b':
+				row = append(row, EmptyCell)
+				m.BatteryZones = append(m.BatteryZones, coord)
+			case 'B':
+				row = append(row, EmptyCell)
+				m.BatterySpawners = append(m.BatterySpawners, coord)
+			case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+				row = append(row, Cell{Type: Portal, Pair: int(c - '0')})
+			case '~':
+				row = append(row, Cell{Type: Lava})
+			case '*':
+				row = append(row, Cell{Type: Ice})
+			case '%':
+				row = append(row, Cell{Type: Slow})
+			default:
+				return nil, GridError.New("unexpected character %q on line %d",
+					c, lineno)
+			}
+		}
+		cells = append(cells, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, GridError.Wrap(err)
+	}
+	if len(cells) == 0 {
+		return nil, GridError.New("empty grid file")
+	}
+	partners, err := findPartners(cells)
+	if err != nil {
+		return nil, err
+	}
+	m.Grid = &Grid{
+		cells:    cells,
+		bounded:  m.bounded,
+		partners: partners,
+	}
+	return m, nil
+}
+
+func (m *Map) setHeader(line string, lineno int) error {
+	parts := strings.SplitN(line, ":", 2)
+	key := strings.ToLower(strings.TrimSpace(parts[0]))
+	value := strings.TrimSpace(parts[1])
+	switch key {
+	case "name":
+		m.Name = value
+	case "author":
+		m.Author = value
+	case "players":
+		players, err := strconv.Atoi(value)
+		if err != nil || players < 1 {
+			return GridError.New("bad player count %q on line %d", value,
+				lineno)
+		}
+		m.Players = players
+	case "preset":
+		m.Preset = value
+	case "topology":
+		switch strings.ToLower(value) {
+		case "wrap":
+			m.bounded = false
+		case "bounded":
+			m.bounded = true
+		default:
+			return GridError.New("unknown topology %q on line %d", value,
+				lineno)
+		}
+	default:
+		return GridError.New("unknown header %q on line %d", key, lineno)
+	}
+	return nil
+}
+
+// Write writes m out in the format ReadMap reads.
+func (m *Map) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	header := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(bw, "%s: %s\n", key, value)
+		}
+	}
+	header("name", m.Name)
+	header("author", m.Author)
+	if m.Players > 0 {
+		header("players", strconv.Itoa(m.Players))
+	}
+	header("preset", m.Preset)
+	if m.Grid.Bounded() {
+		header("topology", "bounded")
+	}
+
+	glyphs := map[Coord]byte{}
+	for _, coord := range m.BatteryZones {
+		glyphs[coord] = 'b'
+	}
+	for _, coord := range m.BatterySpawners {
+		glyphs[coord] = 'B'
+	}
+	for _, spawn := range m.Spawns {
+		glyphs[spawn.Coord] = "^>v<"[spawn.Orientation]
+	}
+	for y, row := range m.Grid.cells {
+		for x, cell := range row {
+			glyph, ok := glyphs[Coord{X: x, Y: y}]
+			switch {
+			case cell.Type == Wall:
+				glyph = 'W'
+			case cell.Type == Brick:
+				glyph = 'D'
+			case cell.Type == Mirror:
+				glyph = byte(mirrorGlyph(cell))
+			case cell.Type == Portal:
+				glyph = byte('0' + cell.Pair)
+			case cell.Hazard():
+				glyph = byte(hazardGlyphs[cell.Type])
+			case !ok:
+				glyph = '_'
+			}
+			bw.WriteByte(glyph)
+		}
+		bw.WriteByte('\n')
+	}
+	return GridError.Wrap(bw.Flush())
+}
+
+// SaveToFile writes m out to path in the format LoadMap reads.
+func (m *Map) SaveToFile(path string) (err error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return GridError.Wrap(err)
+	}
+	defer func() {
+		if close_err := file.Close(); err == nil {
+			err = GridError.Wrap(close_err)
+		}
+	}()
+	return m.Write(file)
+}
+
+// WriteMap writes g out as a map file with nothing but walls in it.
+func (g *Grid) WriteMap(w io.Writer) error {
+	return (&Map{Grid: g}).Write(w)
+}
+
+// SaveToFile writes g out to path as a map file with nothing but walls in
+// it.
+func (g *Grid) SaveToFile(path string) error {
+	return (&Map{Grid: g}).SaveToFile(path)
+}
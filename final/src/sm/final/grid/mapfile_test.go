@@ -0,0 +1,126 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package grid
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReadMap(t *testing.T) {
+	m := readTestMap(t,
+		"# a comment",
+		"name: Test",
+		"author: Somebody",
+		"players: 2",
+		"preset: classic",
+		"topology: bounded",
+		">_W_b",
+		"_D_B_",
+		"b_W_<")
+	if m.Name != "Test" || m.Author != "Somebody" || m.Players != 2 ||
+		m.Preset != "classic" || !m.Grid.Bounded() {
+		t.Errorf("unexpected header %+v", m)
+	}
+	if m.Grid.Width() != 5 || m.Grid.Height() != 3 {
+		t.Errorf("expected a 5x3 grid, got %dx%d", m.Grid.Width(),
+			m.Grid.Height())
+	}
+	spawns := []Spawn{{Coord{X: 0, Y: 0}, East}, {Coord{X: 4, Y: 2}, West}}
+	if len(m.Spawns) != 2 || m.Spawns[0] != spawns[0] ||
+		m.Spawns[1] != spawns[1] {
+		t.Errorf("expected spawns %v, got %v", spawns, m.Spawns)
+	}
+	if len(m.BatteryZones) != 2 || len(m.BatterySpawners) != 1 ||
+		m.BatterySpawners[0] != (Coord{X: 3, Y: 1}) {
+		t.Errorf("unexpected battery zones %v and spawners %v",
+			m.BatteryZones, m.BatterySpawners)
+	}
+	for coord, want := range map[Coord]Type{
+		{X: 0, Y: 0}: Empty,
+		{X: 2, Y: 0}: Wall,
+		{X: 1, Y: 1}: Brick,
+		{X: 3, Y: 1}: Empty,
+	} {
+		if got := m.Grid.CellAt(coord).Type; got != want {
+			t.Errorf("expected %s at %s, got %s", want, coord, got)
+		}
+	}
+}
+
+func TestReadMapOldFormat(t *testing.T) {
+	m := readTestMap(t, "W__", "_W_", "__W")
+	if m.Name != "" || len(m.Spawns) != 0 || m.Grid.Bounded() {
+		t.Errorf("expected nothing but a grid, got %+v", m)
+	}
+	if m.Grid.CellAt(Coord{X: 1, Y: 1}).Type != Wall {
+		t.Error("expected a wall in the middle")
+	}
+}
+
+func TestWriteMap(t *testing.T) {
+	text := strings.Join([]string{
+		"name: Test",
+		"players: 2",
+		"topology: bounded",
+		">_W_b",
+		"1D/B1",
+		"~*%\\<",
+	}, "\n") + "\n"
+	m, err := ReadMap(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := m.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != text {
+		t.Errorf("expected\n%s\ngot\n%s", text, buf.String())
+	}
+}
+
+func TestReadMapErrors(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"# nothing but a comment\n",
+		"___\n__\n",
+		"_x_\n",
+		"color: blue\n___\n",
+		"players: none\n___\n",
+		"players: 0\n___\n",
+		"topology: donut\n___\n",
+		"1__\n",
+		"111\n",
+	} {
+		if _, err := ReadMap(strings.NewReader(text)); err == nil {
+			t.Errorf("%q: expected an error", text)
+		}
+	}
+}
+
+func TestPartner(t *testing.T) {
+	m := readTestMap(t, "1_2", "___", "2_1")
+	deserialized, err := Deserialize(m.Grid.SerializeFor(1), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range []*Grid{m.Grid, deserialized, m.Grid.Clone()} {
+		for _, pair := range [][2]Coord{
+			{{X: 0, Y: 0}, {X: 2, Y: 2}},
+			{{X: 2, Y: 0}, {X: 0, Y: 2}},
+		} {
+			for i := range pair {
+				partner, ok := g.Partner(pair[i])
+				if !ok || partner != pair[1-i] {
+					t.Errorf("expected %s to lead to %s, got %s (%v)", pair[i],
+						pair[1-i], partner, ok)
+				}
+			}
+		}
+		if _, ok := g.Partner(Coord{X: 1, Y: 1}); ok {
+			t.Errorf("expected an empty cell to have no partner")
+		}
+	}
+}
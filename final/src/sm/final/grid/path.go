@@ -0,0 +1,215 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package grid
+
+// Maneuver is one step a tank can take on its way somewhere. Every maneuver
+// takes a turn, so turning costs as much as moving does.
+type Maneuver int
+
+const (
+	Forward   Maneuver = 0
+	TurnLeft  Maneuver = 1
+	TurnRight Maneuver = 2
+)
+
+func (m Maneuver) String() string {
+	switch m {
+	case Forward:
+		return "forward"
+	case TurnLeft:
+		return "left"
+	case TurnRight:
+		return "right"
+	}
+	panic("unreachable")
+}
+
+// Pose is where a tank is and which way it is facing.
+type Pose struct {
+	Coord       Coord
+	Orientation Orientation
+}
+
+// Apply returns the pose a tank ends up in after m, assuming nothing is in
+// its way. Going forward goes through portals, and nowhere at all off the
+// edge of a bounded grid.
+func (g *Grid) Apply(p Pose, m Maneuver) Pose {
+	switch m {
+	case Forward:
+		if next, ok := g.Step(p.Coord, p.Orientation); ok {
+			p.Coord = next
+		}
+	case TurnLeft:
+		p.Orientation.RotateLeft()
+	case TurnRight:
+		p.Orientation.RotateRight()
+	}
+	return p
+}
+
+// Blocked is a set of cells a tank should stay out of on top of the walls,
+// like the cells other tanks are on or cells lasers are about to pass
+// through.
+type Blocked map[Coord]bool
+
+// Passable says whether a tank could drive into coord as the grid is now.
+// Tanks drive through portals, can't get past mirrors, and can't get past
+// bricks until they are knocked out.
+func (g *Grid) Passable(coord Coord) bool {
+	cell := g.cellAt(coord)
+	return cell != nil && !cell.Blocks()
+}
+
+// open says whether a tank could end up on coord. Portals are passable, but
+// tanks only ever go through them, so a portal that comes out right into
+// another portal acts like a wall.
+func (g *Grid) open(coord Coord, blocked Blocked) bool {
+	return g.Passable(coord) && g.cellAt(coord).Type != Portal &&
+		!blocked[coord]
+}
+
+func (g *Grid) poseIndex(p Pose) int {
+	return (p.Coord.Y*g.Width()+p.Coord.X)*4 + int(p.Orientation)
+}
+
+// searchPoses does a breadth first search over every pose reachable from
+// from, calling visit with each pose, its distance in turns, and the index
+// of the pose it was reached from (-1 for from itself). Searching stops
+// early if visit returns false.
+func (g *Grid) searchPoses(from Pose, blocked Blocked,
+	visit func(p Pose, turns, prev int) bool) {
+	seen := make([]bool, g.Width()*g.Height()*4)
+	type entry struct {
+		pose  Pose
+		turns int
+		prev  int
+	}
+	queue := []entry{{pose: from, prev: -1}}
+	seen[g.poseIndex(from)] = true
+	for len(queue) > 0 {
+		e := queue[0]
+		queue = queue[1:]
+		if !visit(e.pose, e.turns, e.prev) {
+			return
+		}
+		for _, m := range []Maneuver{Forward, TurnLeft, TurnRight} {
+			next := g.Apply(e.pose, m)
+			idx := g.poseIndex(next)
+			if seen[idx] || (m == Forward && !g.open(next.Coord, blocked)) {
+				continue
+			}
+			seen[idx] = true
+			queue = append(queue, entry{
+				pose:  next,
+				turns: e.turns + 1,
+				prev:  g.poseIndex(e.pose)})
+		}
+	}
+}
+
+// ShortestPath returns the fewest maneuvers that get a tank in pose from
+// onto to, facing any direction, wrapping around the board if it does and
+// staying out of walls and blocked cells.
+func (g *Grid) ShortestPath(from Pose, to Coord, blocked Blocked) (
+	path []Maneuver, ok bool) {
+	prevs := make(map[int]int)
+	poses := make(map[int]Pose)
+	var end *Pose
+	g.searchPoses(from, blocked, func(p Pose, turns, prev int) bool {
+		idx := g.poseIndex(p)
+		prevs[idx], poses[idx] = prev, p
+		if p.Coord == to {
+			end = &p
+			return false
+		}
+		return true
+	})
+	if end == nil {
+		return nil, false
+	}
+	for idx := g.poseIndex(*end); prevs[idx] >= 0; idx = prevs[idx] {
+		prev := poses[prevs[idx]]
+		cur := poses[idx]
+		switch {
+		case cur.Orientation == prev.Orientation:
+			path = append(path, Forward)
+		case cur.Orientation == prev.Orientation.Left():
+			path = append(path, TurnLeft)
+		default:
+			path = append(path, TurnRight)
+		}
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, true
+}
+
+// DistanceField returns, for every cell indexed [y][x], the fewest turns it
+// takes a tank in pose from to get there, or -1 if it can't.
+func (g *Grid) DistanceField(from Pose, blocked Blocked) [][]int {
+	field := make([][]int, g.Height())
+	for y := range field {
+		field[y] = make([]int, g.Width())
+		for x := range field[y] {
+			field[y][x] = -1
+		}
+	}
+	g.searchPoses(from, blocked, func(p Pose, turns, prev int) bool {
+		if field[p.Coord.Y][p.Coord.X] < 0 {
+			field[p.Coord.Y][p.Coord.X] = turns
+		}
+		return true
+	})
+	return field
+}
+
+// Reachable returns every cell a tank on from could drive to, including
+// from itself.
+func (g *Grid) Reachable(from Coord, blocked Blocked) map[Coord]bool {
+	reachable := map[Coord]bool{from: true}
+	queue := []Coord{from}
+	for len(queue) > 0 {
+		coord := queue[0]
+		queue = queue[1:]
+		for _, next := range g.Neighbors(coord) {
+			if !reachable[next] && g.open(next, blocked) {
+				reachable[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return reachable
+}
+
+// Components splits the open cells of the grid into groups that tanks can
+// drive between, largest first.
+func (g *Grid) Components(blocked Blocked) (components [][]Coord) {
+	seen := map[Coord]bool{}
+	for y := 0; y < g.Height(); y++ {
+		for x := 0; x < g.Width(); x++ {
+			coord := Coord{X: x, Y: y}
+			if seen[coord] || !g.open(coord, blocked) {
+				continue
+			}
+			reachable := g.Reachable(coord, blocked)
+			var component []Coord
+			for y2 := 0; y2 < g.Height(); y2++ {
+				for x2 := 0; x2 < g.Width(); x2++ {
+					other := Coord{X: x2, Y: y2}
+					if reachable[other] {
+						component = append(component, other)
+						seen[other] = true
+					}
+				}
+			}
+			components = append(components, component)
+		}
+	}
+	for i := 1; i < len(components); i++ {
+		for j := i; j > 0 && len(components[j]) > len(components[j-1]); j-- {
+			components[j], components[j-1] = components[j-1], components[j]
+		}
+	}
+	return components
+}
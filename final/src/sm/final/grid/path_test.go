@@ -0,0 +1,136 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package grid
+
+import (
+	"testing"
+)
+
+// walk follows path from from, returning every pose along the way.
+func walk(g *Grid, from Pose, path []Maneuver) (poses []Pose) {
+	poses = append(poses, from)
+	for _, m := range path {
+		from = g.Apply(from, m)
+		poses = append(poses, from)
+	}
+	return poses
+}
+
+func TestShortestPath(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		lines   []string
+		from    Pose
+		to      Coord
+		blocked Blocked
+		// turns is how long the path should be, or -1 if there is none
+		turns int
+	}{
+		{
+			name:  "wraps around",
+			lines: []string{"_W__", "WWWW"},
+			from:  Pose{Coord: Coord{X: 0, Y: 0}, Orientation: West},
+			to:    Coord{X: 2, Y: 0},
+			turns: 2,
+		},
+		{
+			name:  "bounded",
+			lines: []string{"topology: bounded", "_W__", "WWWW"},
+			from:  Pose{Coord: Coord{X: 0, Y: 0}, Orientation: West},
+			to:    Coord{X: 2, Y: 0},
+			turns: -1,
+		},
+		{
+			name:  "turns around at the edge",
+			lines: []string{"topology: bounded", "___"},
+			from:  Pose{Coord: Coord{X: 0, Y: 0}, Orientation: West},
+			to:    Coord{X: 2, Y: 0},
+			turns: 4,
+		},
+		{
+			name:  "through a portal",
+			lines: []string{"W1WWW", "W_W_W", "WWW1W"},
+			from:  Pose{Coord: Coord{X: 1, Y: 1}, Orientation: North},
+			to:    Coord{X: 3, Y: 1},
+			turns: 1,
+		},
+		{
+			name:  "not onto a portal",
+			lines: []string{"W1WWW", "W_W_W", "WWW1W"},
+			from:  Pose{Coord: Coord{X: 1, Y: 1}, Orientation: North},
+			to:    Coord{X: 1, Y: 0},
+			turns: -1,
+		},
+		{
+			name:    "around blocked cells",
+			lines:   []string{"topology: bounded", "___", "___"},
+			from:    Pose{Coord: Coord{X: 0, Y: 0}, Orientation: East},
+			to:      Coord{X: 2, Y: 0},
+			blocked: Blocked{Coord{X: 1, Y: 0}: true},
+			turns:   7,
+		},
+		{
+			name:  "walled in by blocked cells",
+			lines: []string{"___", "___", "___"},
+			from:  Pose{Coord: Coord{X: 1, Y: 1}, Orientation: East},
+			to:    Coord{X: 0, Y: 0},
+			blocked: Blocked{{X: 0, Y: 1}: true, {X: 2, Y: 1}: true,
+				{X: 1, Y: 0}: true, {X: 1, Y: 2}: true},
+			turns: -1,
+		},
+	} {
+		g := readTestMap(t, test.lines...).Grid
+		path, ok := g.ShortestPath(test.from, test.to, test.blocked)
+		if test.turns < 0 {
+			if ok {
+				t.Errorf("%s: expected no path, got %v", test.name, path)
+			}
+			continue
+		}
+		if !ok || len(path) != test.turns {
+			t.Errorf("%s: expected a path %d long, got %v (%v)", test.name,
+				test.turns, path, ok)
+			continue
+		}
+		poses := walk(g, test.from, path)
+		for _, pose := range poses {
+			if !g.open(pose.Coord, test.blocked) {
+				t.Errorf("%s: path %v goes through %s", test.name, path,
+					pose.Coord)
+			}
+		}
+		if end := poses[len(poses)-1].Coord; end != test.to {
+			t.Errorf("%s: path %v ends on %s", test.name, path, end)
+		}
+
+		field := g.DistanceField(test.from, test.blocked)
+		if field[test.to.Y][test.to.X] != test.turns {
+			t.Errorf("%s: expected a distance of %d, got %d", test.name,
+				test.turns, field[test.to.Y][test.to.X])
+		}
+	}
+}
+
+func TestReachable(t *testing.T) {
+	g := readTestMap(t, "W1WWW", "W_W_W", "WWW1W").Grid
+	reachable := g.Reachable(Coord{X: 1, Y: 1}, nil)
+	if len(reachable) != 2 || !reachable[Coord{X: 3, Y: 1}] {
+		t.Fatalf("expected to reach the other side of the portal, got %v",
+			reachable)
+	}
+	if components := g.Components(nil); len(components) != 1 {
+		t.Fatalf("expected the portal to join both cells, got %v",
+			components)
+	}
+
+	g = readTestMap(t, "topology: bounded", "_W_").Grid
+	reachable = g.Reachable(Coord{X: 0, Y: 0}, nil)
+	if len(reachable) != 1 {
+		t.Fatalf("expected not to get past the wall, got %v", reachable)
+	}
+	g.SetBounded(false)
+	reachable = g.Reachable(Coord{X: 0, Y: 0}, nil)
+	if len(reachable) != 2 {
+		t.Fatalf("expected to wrap around the wall, got %v", reachable)
+	}
+}
@@ -10,6 +10,8 @@ type PlayerStatus struct {
 	Moniker string
 	Health  float64
 	Energy  float64
+	// Heat is how close the player is to overheating, from 0 to 1.
+	Heat float64
 }
 
 type MessageType int
@@ -24,4 +26,11 @@ type Renderer interface {
 	Message(msg string, msgType MessageType) error
 	SetStatus(status []PlayerStatus) error
 	Update(cells [][]grid.Cell) error
+	// SetBounded says whether the grid stops at its edges instead of
+	// wrapping around, so things going off one edge aren't drawn as coming
+	// in on the other.
+	SetBounded(bounded bool)
+	// SetTeams says which team each tank is on, so tanks on the same team
+	// are drawn alike.
+	SetTeams(teams map[grid.Owner]int)
 }
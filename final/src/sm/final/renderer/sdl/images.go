@@ -47,7 +47,20 @@ func loadImages(frames, players int) (
 	images map[grid.Cell]*sdl.Surface,
 	rotations map[grid.Owner][]*sdl.Surface, err error) {
 	images = make(map[grid.Cell]*sdl.Surface)
-	for _, simple := range []string{"battery", "floor", "wall"} {
+	for _, simple := range []string{"battery", "floor", "wall", "portal",
+		"brick", "cracked", "energy", "repair", "damage", "speed", "lava", "ice",
+		"slow"} {
+		// every pair of portals looks the same
+		pairs := 1
+		if simple == "portal" {
+			pairs = 10
+		}
+		// and bricks look the same however many times they have been hit,
+		// see getCellImage
+		damage := 0
+		if simple == "cracked" {
+			damage = 1
+		}
 		for _, exploding := range []bool{false, true} {
 			data, err := assets.Asset(fmt.Sprintf("final/images/%s.png", simple))
 			if err != nil {
@@ -70,43 +83,91 @@ func loadImages(frames, players int) (
 			}
 			for orientation := 0; orientation < 4; orientation++ {
 				for owner := 0; owner < players+1; owner++ {
-					images[grid.Cell{
-						Exploding:   exploding,
-						Type:        typeMapping(simple),
-						Orientation: grid.Orientation(orientation),
-						Owner:       grid.Owner(owner)}] = surface
+					for pair := 0; pair < pairs; pair++ {
+						images[grid.Cell{
+							Exploding:   exploding,
+							Type:        typeMapping(simple),
+							Orientation: grid.Orientation(orientation),
+							Owner:       grid.Owner(owner),
+							Pair:        pair,
+							Damage:      damage}] = surface
+					}
 				}
 			}
 		}
 	}
-	for _, rotateable := range []string{"l", "p"} {
+	for _, rotateable := range []string{"l", "p", "c", "m"} {
+		// only tanks have shields
+		shields := []bool{false}
+		if rotateable == "p" {
+			shields = []bool{false, true}
+		}
 		for rotations := 0; rotations < 4; rotations++ {
 			for player := 1; player <= players; player++ {
 				for _, exploding := range []bool{false, true} {
-					surface, err := loadImageRotated(fmt.Sprintf("final/images/%s%d.png",
-						rotateable, (player-1)%2+1), float64(rotations)/4)
-					if err != nil {
-						cleanupImages(images)
-						return nil, nil, err
-					}
-					if exploding {
-						err = overlay(surface, "final/images/ex.png")
+					for _, shielded := range shields {
+						surface, err := loadImageRotated(fmt.Sprintf("final/images/%s%d.png",
+							rotateable, (player-1)%2+1), float64(rotations)/4)
 						if err != nil {
-							surface.Free()
 							cleanupImages(images)
 							return nil, nil, err
 						}
+						if shielded {
+							err = overlay(surface, "final/images/shield.png")
+							if err != nil {
+								surface.Free()
+								cleanupImages(images)
+								return nil, nil, err
+							}
+						}
+						if exploding {
+							err = overlay(surface, "final/images/ex.png")
+							if err != nil {
+								surface.Free()
+								cleanupImages(images)
+								return nil, nil, err
+							}
+						}
+						images[grid.Cell{
+							Exploding:   exploding,
+							Type:        typeMapping(rotateable),
+							Owner:       grid.Owner(player),
+							Orientation: grid.Orientation(rotations),
+							Shielded:    shielded}] = surface
 					}
-					images[grid.Cell{
-						Exploding:   exploding,
-						Type:        typeMapping(rotateable),
-						Owner:       grid.Owner(player),
-						Orientation: grid.Orientation(rotations)}] = surface
 				}
 			}
 		}
 	}
 
+	// mirror.png leans like /, so turning it a quarter of the way around
+	// leans it like \
+	for rotation := 0; rotation < 2; rotation++ {
+		for _, exploding := range []bool{false, true} {
+			surface, err := loadImageRotated("final/images/mirror.png",
+				float64(rotation)/4)
+			if err != nil {
+				cleanupImages(images)
+				return nil, nil, err
+			}
+			if exploding {
+				err = overlay(surface, "final/images/ex.png")
+				if err != nil {
+					surface.Free()
+					cleanupImages(images)
+					return nil, nil, err
+				}
+			}
+			for _, orientation := range []grid.Orientation{
+				grid.Orientation(rotation), grid.Orientation(rotation + 2)} {
+				images[grid.Cell{
+					Exploding:   exploding,
+					Type:        grid.Mirror,
+					Orientation: orientation}] = surface
+			}
+		}
+	}
+
 	rotations = make(map[grid.Owner][]*sdl.Surface)
 	for _, rotateable := range []string{"p"} {
 		for player := 1; player <= players; player++ {
@@ -145,14 +206,36 @@ func typeMapping(name string) grid.Type {
 	switch name {
 	case "battery":
 		return grid.Battery
+	case "energy":
+		return grid.EnergyCell
+	case "repair":
+		return grid.RepairKit
+	case "damage":
+		return grid.DoubleDamage
+	case "speed":
+		return grid.Speed
+	case "lava":
+		return grid.Lava
+	case "ice":
+		return grid.Ice
+	case "slow":
+		return grid.Slow
 	case "floor":
 		return grid.Empty
 	case "wall":
 		return grid.Wall
+	case "portal":
+		return grid.Portal
+	case "brick", "cracked":
+		return grid.Brick
 	case "p":
 		return grid.Player
 	case "l":
 		return grid.Laser
+	case "c":
+		return grid.Charge
+	case "m":
+		return grid.Mine
 	default:
 		panic("unknown type")
 	}
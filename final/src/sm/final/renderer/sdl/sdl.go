@@ -27,6 +27,7 @@ const (
 	frameRate  = 24 // Hz
 	statusSize = 40
 	nickWidth  = 100
+	heatSize   = statusSize / 8
 )
 
 type SDLRenderer struct {
@@ -41,6 +42,8 @@ type SDLRenderer struct {
 	frames           int
 	player_rotations map[grid.Owner][]*sdl.Surface
 	players          int
+	bounded          bool
+	teams            map[grid.Owner]int
 	closed           bool
 }
 
@@ -99,6 +102,7 @@ func NewRenderer(title string, width, height, players int,
 			frame_time:       frame_time,
 			frames:           frames,
 			player_rotations: player_rotations,
+			players:          players,
 			closed:           false}
 		return nil
 	})
@@ -152,32 +156,136 @@ func (r *SDLRenderer) clear() error {
 	return window.FillRect(nil, 0xff000000)
 }
 
-func (r *SDLRenderer) getBehind(m, n int, orientation grid.Orientation) (
-	behind_m, behind_n int) {
-	delta_x, delta_y := orientation.Delta()
-	m -= delta_y
-	n -= delta_x
-	if m < 0 {
-		m += len(r.previous)
+func (r *SDLRenderer) SetBounded(bounded bool) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.bounded = bounded
+}
+
+func (r *SDLRenderer) SetTeams(teams map[grid.Owner]int) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.teams = teams
+}
+
+// healthColours are the colours of the health bars, one per status, starting
+// over if there are more statuses than colours.
+var healthColours = []uint32{0xff06d0ff, 0xffff5a12, 0xffb43cff, 0xffffd700}
+
+// drawStatus draws the status bar for the index'th status in the w wide slot
+// starting at x. Every other bar is mirrored, so with two players they grow
+// in from either side of the window.
+func (r *SDLRenderer) drawStatus(window *sdl.Surface,
+	status renderer.PlayerStatus, index int, x, w int32) error {
+	nick_width := int32(nickWidth)
+	if nick_width > w/3 {
+		nick_width = w / 3
 	}
-	if n < 0 {
-		n += len(r.previous[0])
+	bar_width := w - nick_width
+	mirrored := index%2 == 1
+
+	nick_x := x
+	if mirrored {
+		nick_x = x + w - nick_width
+	}
+	err := r.textToSurface(status.Moniker, window, &sdl.Rect{
+		X: nick_x, W: nick_width, H: statusSize})
+	if err != nil {
+		return err
+	}
+
+	// bar fills a fraction of the bar width, from the moniker outwards
+	bar := func(fraction float64, y, h int32, colour uint32) error {
+		width := int32(fraction * float64(bar_width))
+		bar_x := x + nick_width
+		if mirrored {
+			bar_x = x + bar_width - width
+		}
+		return window.FillRect(&sdl.Rect{X: bar_x, Y: y, W: width, H: h},
+			colour)
+	}
+	err = bar(status.Health, 0, 2*statusSize/3,
+		healthColours[index%len(healthColours)])
+	if err != nil {
+		return err
 	}
-	return m % len(r.previous), n % len(r.previous[0])
+	err = bar(status.Energy, 2*statusSize/3, statusSize/3, 0xff17de9f)
+	if err != nil {
+		return err
+	}
+	// heat runs along the bottom of the energy bar
+	return bar(status.Heat, statusSize-heatSize, heatSize, 0xffe8142b)
 }
 
+// colour returns whose images to draw things owned by owner with. On teams,
+// everybody is drawn like the first player to join with the team's number,
+// so each team has a colour of its own.
+func (r *SDLRenderer) colour(owner grid.Owner) grid.Owner {
+	if team, ok := r.teams[owner]; ok && team > 0 && team <= r.players {
+		return grid.Owner(team)
+	}
+	return owner
+}
+
+// getBehind returns the cell something at m, n facing orientation came from.
+// ok is false if that would be off the edge of a bounded grid.
+func (r *SDLRenderer) getBehind(m, n int, orientation grid.Orientation) (
+	behind_m, behind_n int, ok bool) {
+	delta_x, delta_y := orientation.Delta()
+	return r.offset(m-delta_y, n-delta_x)
+}
+
+// getAhead returns the cell something at m, n facing orientation is headed
+// to. ok is false if that would be off the edge of a bounded grid.
 func (r *SDLRenderer) getAhead(m, n int, orientation grid.Orientation) (
-	behind_m, behind_n int) {
+	ahead_m, ahead_n int, ok bool) {
 	delta_x, delta_y := orientation.Delta()
-	m += delta_y
-	n += delta_x
+	return r.offset(m+delta_y, n+delta_x)
+}
+
+// getSidestep returns the cell the tank at m, n came from if it backed up or
+// strafed there, rather than driving forward. ok is false if it didn't.
+func (r *SDLRenderer) getSidestep(m, n int, cell grid.Cell) (
+	from_m, from_n int, ok bool) {
+	for _, o := range []grid.Orientation{cell.Orientation.Opposite(),
+		cell.Orientation.Left(), cell.Orientation.Right()} {
+		from_m, from_n, ok = r.getBehind(m, n, o)
+		if !ok {
+			continue
+		}
+		from := r.previous[from_m][from_n]
+		if from.Type == grid.Player && from.Owner == cell.Owner &&
+			from.Orientation == cell.Orientation {
+			return from_m, from_n, true
+		}
+	}
+	return 0, 0, false
+}
+
+// step turns how far something moved between neighboring cells into -1, 0
+// or 1, taking into account that it may have wrapped around the board.
+func step(delta int) int32 {
+	switch {
+	case delta > 1:
+		return -1
+	case delta < -1:
+		return 1
+	}
+	return int32(delta)
+}
+
+func (r *SDLRenderer) offset(m, n int) (int, int, bool) {
+	height, width := len(r.previous), len(r.previous[0])
+	if r.bounded && (m < 0 || n < 0 || m >= height || n >= width) {
+		return 0, 0, false
+	}
 	if m < 0 {
-		m += len(r.previous)
+		m += height
 	}
 	if n < 0 {
-		n += len(r.previous[0])
+		n += width
 	}
-	return m % len(r.previous), n % len(r.previous[0])
+	return m % height, n % width, true
 }
 
 func (r *SDLRenderer) Update(cells [][]grid.Cell) error {
@@ -185,7 +293,7 @@ func (r *SDLRenderer) Update(cells [][]grid.Cell) error {
 	var rotations [][]int
 	var shots [][]int
 	explosions := map[grid.Type]bool{}
-	var redraw, powerup bool
+	var redraw, powerup, charged bool
 	var height, width, window_width, window_height int
 	var cell_width, cell_height int32
 	var surface *sdl.Surface
@@ -232,37 +340,60 @@ func (r *SDLRenderer) Update(cells [][]grid.Cell) error {
 					should_redraw = func() bool {
 						prev := r.previous[m][n]
 						if cell.Exploding {
-							// was there an explosion?
-							explosions[prev.Type] = true
+							// was there an explosion? every kind of pickup
+							// sounds like a battery going up
+							if prev.Pickup() {
+								explosions[grid.Battery] = true
+							} else {
+								explosions[prev.Type] = true
+							}
 						}
-						if prev.Type == grid.Battery && cell.Type == grid.Player {
+						if prev.Pickup() && cell.Type == grid.Player {
 							powerup = true
 						}
 
-						behind_m, behind_n := r.getBehind(m, n, cell.Orientation)
-						behind := r.previous[behind_m][behind_n]
+						var behind grid.Cell
+						behind_m, behind_n, ok := r.getBehind(m, n,
+							cell.Orientation)
+						if ok {
+							behind = r.previous[behind_m][behind_n]
+						}
+						var side_m, side_n int
+						var sidestepped bool
+						if cell.Type == grid.Player {
+							side_m, side_n, sidestepped = r.getSidestep(m, n, cell)
+						}
 						switch {
 						case cell == prev:
 							// no change
 							return false
 						case cell.Owner == prev.Owner && cell.Type == prev.Type &&
-							cell.Type == grid.Player && cell.Exploding == prev.Exploding:
+							cell.Type == grid.Player && cell.Exploding == prev.Exploding &&
+							cell.Shielded == prev.Shielded:
 							// player rotation
 							rotations = append(rotations, []int{m, n})
 							return false
 						case cell.Type == behind.Type &&
 							cell.Orientation == behind.Orientation &&
 							cell.Owner == behind.Owner && (cell.Type == grid.Player ||
-							cell.Type == grid.Laser):
+							cell.Type == grid.Laser || cell.Type == grid.Charge):
 							// normal player or laser move
 							moves = append(moves, []int{behind_m, behind_n, m, n})
 							return false
+						case sidestepped && cell.Owner != behind.Owner:
+							// player backing up or strafing
+							moves = append(moves, []int{side_m, side_n, m, n})
+							return false
 						case behind.Owner == cell.Owner &&
 							behind.Orientation == cell.Orientation &&
-							behind.Type == grid.Player && cell.Type == grid.Laser:
+							behind.Type == grid.Player && (cell.Type == grid.Laser ||
+							cell.Type == grid.Charge):
 							// initial laser fire
 							moves = append(moves, []int{behind_m, behind_n, m, n})
 							shots = append(moves, []int{behind_m, behind_n})
+							if cell.Type == grid.Charge {
+								charged = true
+							}
 							return false
 						}
 						return true
@@ -279,13 +410,19 @@ func (r *SDLRenderer) Update(cells [][]grid.Cell) error {
 				}
 			}
 		}
-		if sound, ok := r.sounds["laser1"]; len(shots) > 0 && ok {
+		if sound, ok := r.sounds["charge1"]; charged && ok {
+			sound.PlayTimed(1, 0, 0)
+		} else if sound, ok := r.sounds["laser1"]; len(shots) > 0 && ok {
 			sound.PlayTimed(1, 0, 0)
 		}
+		if sound, ok := r.sounds["mine1"]; explosions[grid.Mine] && ok {
+			sound.PlayTimed(0, 0, 0)
+		}
 		if sound, ok := r.sounds["playerhit1"]; explosions[grid.Player] && ok {
 			sound.PlayTimed(2, 0, 0)
 		} else {
-			if sound, ok := r.sounds["wallhit1"]; explosions[grid.Wall] && ok {
+			if sound, ok := r.sounds["wallhit1"]; (explosions[grid.Wall] ||
+				explosions[grid.Brick]) && ok {
 				sound.PlayTimed(3, 0, 0)
 			}
 		}
@@ -338,7 +475,7 @@ func (r *SDLRenderer) Update(cells [][]grid.Cell) error {
 						angle += r.frames * 4
 					}
 					angle %= r.frames * 4
-					cell_surface := r.player_rotations[cell.Owner][angle]
+					cell_surface := r.player_rotations[r.colour(cell.Owner)][angle]
 
 					err = r.drawCell(surface, cell_surface,
 						int32(n)*cell_width, int32(m)*cell_height, cell_width, cell_height,
@@ -351,11 +488,11 @@ func (r *SDLRenderer) Update(cells [][]grid.Cell) error {
 				for _, mv := range moves {
 					old_m, old_n, new_m, new_n := mv[0], mv[1], mv[2], mv[3]
 					cell := cells[new_m][new_n]
-					delta_x, delta_y := cell.Orientation.Delta()
+					delta_x, delta_y := step(new_n-old_n), step(new_m-old_m)
 					new_x := int32(old_n)*cell_width +
-						cell_width*int32(frame)*int32(delta_x)/int32(r.frames)
+						cell_width*int32(frame)*delta_x/int32(r.frames)
 					new_y := int32(old_m)*cell_height +
-						cell_height*int32(frame)*int32(delta_y)/int32(r.frames)
+						cell_height*int32(frame)*delta_y/int32(r.frames)
 					err = r.drawCell(surface, r.getCellImage(cell), new_x, new_y,
 						cell_width, cell_height, true)
 					if err != nil {
@@ -400,6 +537,12 @@ func (r *SDLRenderer) Update(cells [][]grid.Cell) error {
 }
 
 func (r *SDLRenderer) getCellImage(cell grid.Cell) *sdl.Surface {
+	if cell.Damage > 1 {
+		cell.Damage = 1
+	}
+	if cell.Owner > 0 {
+		cell.Owner = r.colour(cell.Owner)
+	}
 	rv := r.images[cell]
 	if rv == nil {
 		logger.Critf("unknown cell type: %#v", cell)
@@ -553,6 +696,9 @@ func (r *SDLRenderer) SetStatus(statuses []renderer.PlayerStatus) error {
 			if status.Energy < 0 || status.Energy > 1 {
 				return fmt.Errorf("energy should be between 0 and 1")
 			}
+			if status.Heat < 0 || status.Heat > 1 {
+				return fmt.Errorf("heat should be between 0 and 1")
+			}
 		}
 
 		window, err := r.window.GetSurface()
@@ -566,60 +712,14 @@ func (r *SDLRenderer) SetStatus(statuses []renderer.PlayerStatus) error {
 			return err
 		}
 
-		bar_width := window.W/2 - nickWidth
-
-		if len(statuses) > 0 {
-			p1 := statuses[0]
-			err = r.textToSurface(p1.Moniker, window, &sdl.Rect{
-				W: nickWidth, H: statusSize})
-			if err != nil {
-				logger.Crit("here")
-				return err
-			}
-			err = window.FillRect(&sdl.Rect{
-				X: nickWidth,
-				W: int32(p1.Health * float64(bar_width)),
-				H: 2 * statusSize / 3}, 0xff06d0ff)
-			if err != nil {
-				logger.Crit("here")
-				return err
-			}
-			err = window.FillRect(&sdl.Rect{
-				X: nickWidth,
-				Y: 2 * statusSize / 3,
-				W: int32(p1.Energy * float64(bar_width)),
-				H: statusSize / 3}, 0xff17de9f)
-			if err != nil {
-				logger.Crit("here")
-				return err
-			}
+		if len(statuses) == 0 {
+			return nil
 		}
-
-		if len(statuses) > 1 {
-			p2 := statuses[1]
-			err = r.textToSurface(p2.Moniker, window, &sdl.Rect{
-				X: window.W - nickWidth, W: nickWidth, H: statusSize})
-			if err != nil {
-				logger.Crit("here")
-				return err
-			}
-			p2health_width := int32(p2.Health * float64(bar_width))
-			p2energy_width := int32(p2.Energy * float64(bar_width))
-			err = window.FillRect(&sdl.Rect{
-				X: window.W - nickWidth - p2health_width,
-				W: p2health_width,
-				H: 2 * statusSize / 3}, 0xffff5a12)
-			if err != nil {
-				logger.Crit("here")
-				return err
-			}
-			err = window.FillRect(&sdl.Rect{
-				Y: 2 * statusSize / 3,
-				X: window.W - nickWidth - p2energy_width,
-				W: p2energy_width,
-				H: statusSize / 3}, 0xff17de9f)
+		slot_width := window.W / int32(len(statuses))
+		for i, status := range statuses {
+			err = r.drawStatus(window, status, i, int32(i)*slot_width,
+				slot_width)
 			if err != nil {
-				logger.Crit("here")
 				return err
 			}
 		}
@@ -0,0 +1,174 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+
+	"sm/final/game"
+)
+
+// LineServer serves games over a plain newline-delimited protocol, for bots
+// written in languages without a usable http client. A client first sends
+//
+//	JOIN <game> <moniker> [map=<map>] [team=<team>] [handicap=<handicap>]
+//
+// with the same options as an http join, and then a single command word per
+// line for each turn. The server answers the join and every command with the
+// resulting TurnState as a single line of JSON, and hangs up once the game is
+// over. Errors are reported as a JSON object with an "error" field before
+// hanging up.
+type LineServer struct {
+	games *game.Games
+}
+
+func NewLineServer(games *game.Games) *LineServer {
+	return &LineServer{
+		games: games,
+	}
+}
+
+// Serve accepts connections on l until it fails. l may be any listener,
+// including a crypto/tls one for encrypted transport.
+func (s *LineServer) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+type lineConn struct {
+	mtx  sync.Mutex
+	conn net.Conn
+}
+
+func (c *lineConn) writeLine(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return internalServerError.Wrap(err)
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	_, err = c.conn.Write(append(data, '\n'))
+	return err
+}
+
+func (c *lineConn) writeState(state game.TurnState) error {
+	return c.writeLine(state)
+}
+
+func (c *lineConn) writeError(err error) {
+	logger.Errore(c.writeLine(struct {
+		Error string `json:"error"`
+	}{Error: err.Error()}))
+}
+
+func (s *LineServer) serveConn(conn net.Conn) {
+	defer conn.Close()
+	logger.Noticef(">>> line connection from %s", conn.RemoteAddr())
+	err := s.play(&lineConn{conn: conn})
+	logger.Noticef("<<< line connection from %s (%v)", conn.RemoteAddr(), err)
+}
+
+func (s *LineServer) play(conn *lineConn) (err error) {
+	scanner := bufio.NewScanner(conn.conn)
+	nextLine := func() (string, error) {
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" {
+				return line, nil
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", badRequestError.New("connection closed")
+	}
+
+	line, err := nextLine()
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.EqualFold(fields[0], string(game.Join)) {
+		err = badRequestError.New(
+			"expected JOIN <game> <moniker> [option=value...], got %q", line)
+		conn.writeError(err)
+		return err
+	}
+	name, moniker := fields[1], fields[2]
+	options, err := parseLineOptions(fields[3:])
+	if err != nil {
+		conn.writeError(err)
+		return err
+	}
+	opts, err := parseJoinOptions(options["team"], options["handicap"])
+	if err != nil {
+		conn.writeError(err)
+		return err
+	}
+
+	thegame, _, err := lookupOrCreate(s.games, name, options["map"])
+	if err != nil {
+		conn.writeError(err)
+		return err
+	}
+	player_id, state, err := thegame.JoinWith(moniker, opts)
+	if err != nil {
+		conn.writeError(err)
+		return badRequestError.Wrap(err)
+	}
+	err = conn.writeState(state)
+	if err != nil {
+		return err
+	}
+
+	return relayTurns(thegame, player_id,
+		func() (game.Command, error) {
+			line, err := nextLine()
+			if err != nil {
+				return "", err
+			}
+			command, err := game.CommandFromString(line)
+			if err != nil || command == game.Join {
+				err = badRequestError.New("invalid command %q", line)
+				conn.writeError(err)
+				return "", err
+			}
+			return command, nil
+		},
+		conn.writeState,
+		func(game.TurnState) {
+			conn.conn.Close()
+		},
+		func(err error) {
+			conn.writeError(err)
+			conn.conn.Close()
+		})
+}
+
+// parseLineOptions parses the key=value options after a JOIN.
+func parseLineOptions(fields []string) (options map[string]string,
+	err error) {
+	options = map[string]string{}
+	for _, field := range fields {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			return nil, badRequestError.New("bad join option %q", field)
+		}
+		switch parts[0] {
+		case "map", "team", "handicap":
+			options[parts[0]] = parts[1]
+		default:
+			return nil, badRequestError.New("unknown join option %q", parts[0])
+		}
+	}
+	return options, nil
+}
@@ -0,0 +1,169 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sm/final/game"
+)
+
+// serveLines serves games over the line protocol until l is closed.
+func serveLines(t *testing.T, games *game.Games) (l net.Listener) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go NewLineServer(games).Serve(l)
+	return l
+}
+
+type lineClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialLine(t *testing.T, l net.Listener, join string) *lineClient {
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &lineClient{conn: conn, reader: bufio.NewReader(conn)}
+	c.send(t, join)
+	return c
+}
+
+func (c *lineClient) send(t *testing.T, line string) {
+	_, err := fmt.Fprintf(c.conn, "%s\n", line)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (c *lineClient) receive(t *testing.T) (reply stateReply, err error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return reply, err
+	}
+	err = json.Unmarshal([]byte(line), &reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reply, nil
+}
+
+// newTestPool returns a map pool with a single small map called small.
+func newTestPool(t *testing.T) (pool *game.MapPool, dir string) {
+	dir, err := ioutil.TempDir("", "lines")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "small.map"),
+		[]byte(">__\n___\n__<\n"), 0644)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	pool, err = game.LoadMapPool(dir, game.RotationOrder, "")
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return pool, dir
+}
+
+func TestLineJoinOptions(t *testing.T) {
+	config := newTestConfig(300)
+	config.Teams = 2
+	games := game.NewGames(config)
+	pool, dir := newTestPool(t)
+	defer os.RemoveAll(dir)
+	games.SetMapPool(pool)
+	l := serveLines(t, games)
+	defer l.Close()
+
+	a := dialLine(t, l,
+		"JOIN options a map=small team=2 handicap=health=450,max-health=450")
+	defer a.conn.Close()
+	b := dialLine(t, l, "join options b team=1")
+	defer b.conn.Close()
+
+	reply, err := a.receive(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Error != "" || reply.Status != game.Running {
+		t.Fatalf("expected to join a running game, got %+v", reply)
+	}
+	if reply.Team != 2 || reply.Health != 450 ||
+		reply.Config.MaxHealth != 450 || reply.Config.Map != "small" {
+		t.Fatalf("expected a handicapped player on team 2 on small, got %+v",
+			reply)
+	}
+	reply, err = b.receive(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Team != 1 || reply.Health != 300 {
+		t.Fatalf("expected a regular player on team 1, got %+v", reply)
+	}
+
+	a.send(t, "noop")
+	b.send(t, "NOOP")
+	for _, c := range []*lineClient{a, b} {
+		reply, err := c.receive(t)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reply.Error != "" || reply.Status != game.Running {
+			t.Fatalf("expected the game to go on, got %+v", reply)
+		}
+	}
+}
+
+func TestLineBadJoin(t *testing.T) {
+	games := game.NewGames(newTestConfig(300))
+	pool, dir := newTestPool(t)
+	defer os.RemoveAll(dir)
+	games.SetMapPool(pool)
+	l := serveLines(t, games)
+	defer l.Close()
+
+	for _, test := range []struct {
+		join  string
+		error string
+	}{
+		{"HELLO", "expected JOIN"},
+		{"JOIN bad", "expected JOIN"},
+		{"JOIN bad a colour=red", `unknown join option "colour"`},
+		{"JOIN bad a team", `bad join option "team"`},
+		{"JOIN bad a team=blue", `bad team "blue"`},
+		{"JOIN bad a handicap=speed=2", `unknown handicap "speed"`},
+		{"JOIN bad a map=nowhere", `no map called "nowhere"`},
+	} {
+		c := dialLine(t, l, test.join)
+		reply, err := c.receive(t)
+		if err != nil {
+			t.Fatalf("%s: %v", test.join, err)
+		}
+		if !strings.Contains(reply.Error, test.error) {
+			t.Fatalf("%s: expected an error with %q, got %+v", test.join,
+				test.error, reply)
+		}
+		_, err = c.receive(t)
+		if err != io.EOF {
+			t.Fatalf("%s: expected the server to hang up, got %v", test.join,
+				err)
+		}
+		c.conn.Close()
+	}
+}
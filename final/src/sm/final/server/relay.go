@@ -0,0 +1,64 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package server
+
+import (
+	"sync"
+
+	"sm/final/game"
+)
+
+// relayTurns plays a joined player's game over a persistent connection. Every
+// command returned by next is submitted as soon as it arrives, just like
+// concurrent http requests would be, so that duplicate actions within a turn
+// get the same self-destruct treatment. Each resulting state is passed to
+// send. Once, either finish is called with the first state of a finished
+// game, or fail is called with the first error taking a turn or sending its
+// state; both should hang up the connection so that next fails, and fail
+// should tell the client what went wrong first. relayTurns returns nil if
+// the game finished, the error passed to fail, or the error from next
+// otherwise.
+func relayTurns(thegame *game.Game, player_id string,
+	next func() (game.Command, error), send func(game.TurnState) error,
+	finish func(game.TurnState), fail func(error)) error {
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	// failure is only set before finished is closed
+	var failure error
+	finished := make(chan struct{})
+	defer wg.Wait()
+
+	for {
+		command, err := next()
+		if err != nil {
+			select {
+			case <-finished:
+				return failure
+			default:
+				return err
+			}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			state, err := thegame.TakeTurn(player_id, command)
+			if err == nil {
+				err = send(state)
+			}
+			switch {
+			case err != nil:
+				once.Do(func() {
+					failure = err
+					close(finished)
+					fail(err)
+				})
+			case state.Status != game.Running:
+				once.Do(func() {
+					close(finished)
+					finish(state)
+				})
+			}
+		}()
+	}
+}
@@ -5,6 +5,7 @@ package server
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"github.com/jtolds/go-oauth2http/utils"
 	"github.com/spacemonkeygo/errors"
@@ -29,9 +30,13 @@ var (
 
 const PlayerIdHeader = "X-SM-PlayerId"
 const PlayerMonikerHeader = "X-SM-PlayerMoniker"
+const PlayerTeamHeader = "X-SM-PlayerTeam"
+const PlayerHandicapHeader = "X-SM-PlayerHandicap"
 
 type Server struct {
-	games *game.Games
+	games     *game.Games
+	opponents map[string]bool
+	attach    func(g *game.Game, name string) error
 }
 
 func New(games *game.Games) *Server {
@@ -40,6 +45,119 @@ func New(games *game.Games) *Server {
 	}
 }
 
+// SetOpponents lets players ask for one of the built-in opponents called
+// names by adding an opponent parameter to the join request that starts a
+// game. attach is called with the game and the name of the requested
+// opponent once that player has joined, and should add the opponent as a
+// player.
+func (s *Server) SetOpponents(names []string,
+	attach func(g *game.Game, name string) error) {
+	s.opponents = map[string]bool{}
+	for _, name := range names {
+		s.opponents[name] = true
+	}
+	s.attach = attach
+}
+
+// opponent returns the name of the built-in opponent r asks for, if any.
+func (s *Server) opponent(r *http.Request) (string, error) {
+	name := r.URL.Query().Get("opponent")
+	if name == "" {
+		return "", nil
+	}
+	if s.attach == nil {
+		return "", badRequestError.New("built-in opponents are not available")
+	}
+	if !s.opponents[name] {
+		return "", badRequestError.New("no such opponent %q", name)
+	}
+	return name, nil
+}
+
+// attachOpponent adds the built-in opponent called name to thegame, unless
+// name is "" or the game was already there before this request.
+func (s *Server) attachOpponent(thegame *game.Game, created bool,
+	name string) error {
+	if name == "" || !created {
+		return nil
+	}
+	err := s.attach(thegame, name)
+	if err != nil {
+		return badRequestError.Wrap(err)
+	}
+	return nil
+}
+
+// joinOptions returns what r asks for when joining: a team with the
+// X-SM-PlayerTeam header or the team parameter, and a handicap with the
+// X-SM-PlayerHandicap header or the handicap parameter.
+func joinOptions(r *http.Request) (opts game.JoinOptions, err error) {
+	team := r.Header.Get(PlayerTeamHeader)
+	if team == "" {
+		team = r.URL.Query().Get("team")
+	}
+	handicap := r.Header.Get(PlayerHandicapHeader)
+	if handicap == "" {
+		handicap = r.URL.Query().Get("handicap")
+	}
+	return parseJoinOptions(team, handicap)
+}
+
+// parseJoinOptions parses the team and handicap a player asked for, either of
+// which may be "" to leave it up to the game.
+func parseJoinOptions(team, handicap string) (opts game.JoinOptions,
+	err error) {
+	if team != "" {
+		opts.Team, err = strconv.Atoi(team)
+		if err != nil {
+			return opts, badRequestError.New("bad team %q", team)
+		}
+	}
+	if handicap != "" {
+		parsed, err := game.ParseHandicap(handicap)
+		if err != nil {
+			return opts, badRequestError.Wrap(err)
+		}
+		opts.Handicap = &parsed
+	}
+	return opts, nil
+}
+
+// lookupOrCreate finds or creates the game called name, on the map asked
+// for with the map parameter, if any. created is true if it is a new game.
+func (s *Server) lookupOrCreate(r *http.Request, name string) (
+	thegame *game.Game, created bool, err error) {
+	return lookupOrCreate(s.games, name, r.URL.Query().Get("map"))
+}
+
+// lookupOrCreate finds or creates the game called name in games, on the map
+// called map_name, or any map if map_name is "".
+func lookupOrCreate(games *game.Games, name, map_name string) (
+	thegame *game.Game, created bool, err error) {
+	thegame, created, err = games.LookupOrCreate(name, map_name)
+	if game.MapError.Contains(err) {
+		return nil, false, badRequestError.Wrap(err)
+	}
+	if err != nil {
+		return nil, false, internalServerError.Wrap(err)
+	}
+	return thegame, created, nil
+}
+
+func (s *Server) serveList(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" {
+		return methodNotAllowedError.New("%s", r.Method)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	list_bytes, err := json.MarshalIndent(s.games.List(), "", "\t")
+	if err != nil {
+		return internalServerError.Wrap(err)
+	}
+	_, err = w.Write(list_bytes)
+	logger.Errore(err)
+	return nil
+}
+
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	logger.Noticef(">>> %s %s", r.Method, r.URL)
 	err := s.serveGame(w, r)
@@ -55,12 +173,16 @@ func (s *Server) serveGame(w http.ResponseWriter, r *http.Request) (err error) {
 	name, left := utils.Shift(r.URL.Path)
 	action, left := utils.Shift(left)
 	switch {
+	case name == "" && left == "":
+		return s.serveList(w, r)
 	case name == "":
 		return notFoundError.New("%s", r.URL.Path)
 	case action == "":
 		return notFoundError.New("%s", r.URL.Path)
 	case left != "":
 		return notFoundError.New("%s", r.URL.Path)
+	case action == WebSocketAction:
+		return s.serveWebSocket(w, r, name)
 	}
 	switch r.Method {
 	case "POST":
@@ -78,14 +200,27 @@ func (s *Server) serveGame(w http.ResponseWriter, r *http.Request) (err error) {
 			if moniker == "" {
 				return badRequestError.New("missing X-SM-PlayerMoniker header")
 			}
-			thegame, err := s.games.LookupOrCreate(name)
+			opts, err := joinOptions(r)
 			if err != nil {
-				return internalServerError.Wrap(err)
+				return err
 			}
-			player_id, state, err = thegame.Join(moniker)
+			opponent, err := s.opponent(r)
+			if err != nil {
+				return err
+			}
+			thegame, created, err := s.lookupOrCreate(r, name)
+			if err != nil {
+				return err
+			}
+			seat, err := thegame.TakeSeat(moniker, opts)
 			if err != nil {
 				return badRequestError.Wrap(err)
 			}
+			err = s.attachOpponent(thegame, created, opponent)
+			if err != nil {
+				return err
+			}
+			player_id, state = seat.Id, seat.Wait()
 		} else {
 			thegame := s.games.Lookup(name)
 			if thegame == nil {
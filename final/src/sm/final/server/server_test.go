@@ -0,0 +1,108 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"sm/final/game"
+)
+
+// postJoin joins the game at path (like /name/join?opponent=dummy) as
+// moniker, and returns the response status.
+func postJoin(t *testing.T, ts *httptest.Server, path, moniker string) (
+	status int) {
+	req, err := http.NewRequest("POST", ts.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(PlayerMonikerHeader, moniker)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+// dummyOpponents counts how often an opponent is attached, and seats one
+// that never takes a turn, or fails with fail if it isn't nil.
+type dummyOpponents struct {
+	mtx      sync.Mutex
+	attached int
+	fail     error
+}
+
+func (d *dummyOpponents) attach(g *game.Game, name string) error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	d.attached++
+	if d.fail != nil {
+		return d.fail
+	}
+	_, err := g.TakeSeat(name, game.JoinOptions{})
+	return err
+}
+
+func TestOpponentOnlyForNewGames(t *testing.T) {
+	config := newTestConfig(300)
+	config.NumPlayers = 3
+	games := game.NewGames(config)
+	s := New(games)
+	opponents := &dummyOpponents{}
+	s.SetOpponents([]string{"dummy"}, opponents.attach)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	statuses := make(chan int)
+	for _, moniker := range []string{"a", "b"} {
+		go func(moniker string) {
+			statuses <- postJoin(t, ts, "/new/join?opponent=dummy", moniker)
+		}(moniker)
+	}
+	for i := 0; i < 2; i++ {
+		if status := <-statuses; status != http.StatusOK {
+			t.Fatalf("expected both players to join, got status %d", status)
+		}
+	}
+	if opponents.attached != 1 {
+		t.Fatalf("expected one opponent, got %d", opponents.attached)
+	}
+}
+
+func TestOpponentErrors(t *testing.T) {
+	games := game.NewGames(newTestConfig(300))
+	s := New(games)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	if postJoin(t, ts, "/none/join?opponent=dummy", "a") == http.StatusOK {
+		t.Fatalf("expected opponents to be unavailable")
+	}
+	if games.Lookup("none") != nil {
+		t.Fatalf("expected no game without opponents")
+	}
+
+	opponents := &dummyOpponents{fail: fmt.Errorf("no room")}
+	s.SetOpponents([]string{"dummy"}, opponents.attach)
+	if postJoin(t, ts, "/unknown/join?opponent=nobody", "a") ==
+		http.StatusOK {
+		t.Fatalf("expected an unknown opponent to fail")
+	}
+	if games.Lookup("unknown") != nil {
+		t.Fatalf("expected no game for an unknown opponent")
+	}
+
+	if postJoin(t, ts, "/failing/join?opponent=dummy", "a") ==
+		http.StatusOK {
+		t.Fatalf("expected the opponent's error")
+	}
+	if opponents.attached != 1 {
+		t.Fatalf("expected one try at an opponent, got %d",
+			opponents.attached)
+	}
+}
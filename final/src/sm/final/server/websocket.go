@@ -0,0 +1,145 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package server
+
+import (
+	"io"
+	"net/http"
+	"sync"
+
+	"golang.org/x/net/websocket"
+
+	"sm/final/game"
+)
+
+const WebSocketAction = "ws"
+
+// WebSocketCommand is the message a client sends over the websocket to take
+// its turn.
+type WebSocketCommand struct {
+	Command string `json:"command"`
+}
+
+// wsConn serializes writes to a websocket, since a player's turns may be
+// answered out of order if they submit more than one action per turn.
+type wsConn struct {
+	mtx    sync.Mutex
+	conn   *websocket.Conn
+	closed bool
+}
+
+func (c *wsConn) writeState(state game.TurnState) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return websocket.JSON.Send(c.conn, state)
+}
+
+// fail tells the client what went wrong with a message with an "error"
+// field, like the line protocol does, and hangs up.
+func (c *wsConn) fail(err error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.closed {
+		return
+	}
+	logger.Errore(websocket.JSON.Send(c.conn, struct {
+		Error string `json:"error"`
+	}{Error: err.Error()}))
+	c.hangUp()
+}
+
+func (c *wsConn) close() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if !c.closed {
+		c.hangUp()
+	}
+}
+
+func (c *wsConn) hangUp() {
+	c.closed = true
+	logger.Errore(c.conn.Close())
+}
+
+func (s *Server) serveWebSocket(w http.ResponseWriter, r *http.Request,
+	name string) (err error) {
+	if r.Method != "GET" {
+		return methodNotAllowedError.New("%s", r.Method)
+	}
+	moniker := r.Header.Get(PlayerMonikerHeader)
+	if moniker == "" {
+		moniker = r.URL.Query().Get("moniker")
+	}
+	if moniker == "" {
+		return badRequestError.New(
+			"missing X-SM-PlayerMoniker header or moniker parameter")
+	}
+	opts, err := joinOptions(r)
+	if err != nil {
+		return err
+	}
+	opponent, err := s.opponent(r)
+	if err != nil {
+		return err
+	}
+	thegame, created, err := s.lookupOrCreate(r, name)
+	if err != nil {
+		return err
+	}
+
+	// once the connection is upgraded we can no longer respond with an http
+	// error, so everything past this point is reported over the websocket.
+	// websocket.Server, unlike websocket.Handler, doesn't check the origin,
+	// so bots can connect from anywhere.
+	websocket.Server{Handler: func(ws *websocket.Conn) {
+		err := playWebSocket(thegame, moniker, opts, &wsConn{conn: ws},
+			func() error {
+				return s.attachOpponent(thegame, created, opponent)
+			})
+		if err != nil && err != io.EOF {
+			logger.Errore(err)
+		}
+	}}.ServeHTTP(w, r)
+	return nil
+}
+
+// playWebSocket joins thegame and plays it over conn. joined is called once
+// the player has joined, before the game starts.
+func playWebSocket(thegame *game.Game, moniker string, opts game.JoinOptions,
+	conn *wsConn, joined func() error) error {
+	seat, err := thegame.TakeSeat(moniker, opts)
+	if err != nil {
+		conn.fail(err)
+		return nil
+	}
+	if err := joined(); err != nil {
+		conn.fail(err)
+		return err
+	}
+	player_id, state := seat.Id, seat.Wait()
+	if err := conn.writeState(state); err != nil {
+		conn.fail(err)
+		return err
+	}
+
+	return relayTurns(thegame, player_id,
+		func() (game.Command, error) {
+			var msg WebSocketCommand
+			err := websocket.JSON.Receive(conn.conn, &msg)
+			if err != nil {
+				return "", err
+			}
+			command, err := game.CommandFromString(msg.Command)
+			if err != nil || command == game.Join {
+				err = badRequestError.New("invalid command %q", msg.Command)
+				conn.fail(err)
+				return "", err
+			}
+			return command, nil
+		},
+		conn.writeState,
+		func(game.TurnState) {
+			conn.close()
+		},
+		conn.fail)
+}
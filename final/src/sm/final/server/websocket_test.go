@@ -0,0 +1,152 @@
+// Copyright (C) 2015 Space Monkey, Inc.
+
+package server
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/websocket"
+
+	"sm/final/game"
+)
+
+// newTestConfig returns a config for two players on a small random grid,
+// where everybody starts out with health.
+func newTestConfig(health int) *game.Config {
+	config := game.DefaultConfig()
+	config.NumPlayers = 2
+	config.Width, config.Height = 8, 8
+	config.GridFile, config.MapDir = "", ""
+	config.PlayerHealth = health
+	return config
+}
+
+type stateReply struct {
+	game.TurnState
+	Error string `json:"error"`
+}
+
+func dialWebSocket(t *testing.T, ts *httptest.Server, path string) (
+	conn *websocket.Conn) {
+	conn, err := websocket.Dial(
+		"ws://"+strings.TrimPrefix(ts.URL, "http://")+path, "", ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func receiveState(t *testing.T, conn *websocket.Conn) stateReply {
+	var reply stateReply
+	err := websocket.JSON.Receive(conn, &reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reply
+}
+
+// joinWebSocket has two players join name over websockets, and returns
+// their connections once the game has started.
+func joinWebSocket(t *testing.T, ts *httptest.Server, name string) (
+	conns []*websocket.Conn) {
+	for _, moniker := range []string{"a", "b"} {
+		conns = append(conns,
+			dialWebSocket(t, ts, "/"+name+"/ws?moniker="+moniker))
+	}
+	for _, conn := range conns {
+		reply := receiveState(t, conn)
+		if reply.Error != "" || reply.Status != game.Running {
+			t.Fatalf("expected to join a running game, got %+v", reply)
+		}
+		if reply.Config == nil || reply.Config.MaxHealth == 0 {
+			t.Fatalf("expected the join to come with a config")
+		}
+	}
+	return conns
+}
+
+func TestWebSocketTurns(t *testing.T) {
+	ts := httptest.NewServer(New(game.NewGames(newTestConfig(300))))
+	defer ts.Close()
+	conns := joinWebSocket(t, ts, "turns")
+	defer conns[1].Close()
+
+	for turn := 0; turn < 2; turn++ {
+		for _, conn := range conns {
+			err := websocket.JSON.Send(conn,
+				WebSocketCommand{Command: string(game.Noop)})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, conn := range conns {
+			reply := receiveState(t, conn)
+			if reply.Error != "" || reply.Status != game.Running {
+				t.Fatalf("expected the game to go on, got %+v", reply)
+			}
+		}
+	}
+
+	err := websocket.JSON.Send(conns[0], WebSocketCommand{Command: "jump"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := receiveState(t, conns[0])
+	if !strings.Contains(reply.Error, `invalid command "jump"`) {
+		t.Fatalf("expected an invalid command error, got %+v", reply)
+	}
+	err = websocket.JSON.Receive(conns[0], &reply)
+	if err != io.EOF {
+		t.Fatalf("expected the server to hang up, got %v", err)
+	}
+}
+
+func TestWebSocketGameOver(t *testing.T) {
+	// everybody runs out of health on the first tick
+	ts := httptest.NewServer(New(game.NewGames(newTestConfig(1))))
+	defer ts.Close()
+	conns := joinWebSocket(t, ts, "over")
+
+	for _, conn := range conns {
+		err := websocket.JSON.Send(conn,
+			WebSocketCommand{Command: string(game.Noop)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, conn := range conns {
+		reply := receiveState(t, conn)
+		if reply.Status != game.Draw {
+			t.Fatalf("expected a draw, got %+v", reply)
+		}
+		var more stateReply
+		err := websocket.JSON.Receive(conn, &more)
+		if err != io.EOF {
+			t.Fatalf("expected the server to hang up, got %v", err)
+		}
+	}
+}
+
+func TestWebSocketBadJoin(t *testing.T) {
+	ts := httptest.NewServer(New(game.NewGames(newTestConfig(300))))
+	defer ts.Close()
+
+	_, err := websocket.Dial("ws://"+strings.TrimPrefix(ts.URL, "http://")+
+		"/bad/ws", "", ts.URL)
+	if err == nil {
+		t.Fatalf("expected joining without a moniker to fail")
+	}
+
+	// joining a game that has already started is only found out about
+	// after the upgrade, so it comes over the websocket.
+	joinWebSocket(t, ts, "full")
+	conn := dialWebSocket(t, ts, "/full/ws?moniker=c")
+	defer conn.Close()
+	reply := receiveState(t, conn)
+	if reply.Error == "" {
+		t.Fatalf("expected an error joining a full game, got %+v", reply)
+	}
+}